@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// defaultLines is how many lines head and tail print unless told otherwise
+const defaultLines = 10
+
+// tailChunk is how much of the end of a file tail fetches at first; it asks
+// for four times as much each time that does not hold enough lines
+const tailChunk = 64 << 10
+
+// catOptions are the flags of cat, head and tail
+type catOptions struct {
+	offset int64
+	length int64 // -1 for the rest of the file
+	lines  int
+}
+
+// parseCatArgs separates the flags of cat (--offset, --length) or head and
+// tail (-n) from the remote paths
+func parseCatArgs(cmd string, args []string) (catOptions, []string, error) {
+	opts := catOptions{length: -1, lines: defaultLines}
+	var paths []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch {
+		case cmd == "cat" && (name == "--offset" || name == "--length"),
+			cmd != "cat" && (name == "-n" || name == "--lines"):
+		default:
+			paths = append(paths, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "--offset":
+			n, err := parseSize(strings.TrimPrefix(value, "-"))
+			if err != nil {
+				return opts, nil, fmt.Errorf("invalid offset %q", value)
+			}
+			if strings.HasPrefix(value, "-") {
+				n = -n
+			}
+			opts.offset = n
+		case "--length":
+			n, err := parseSize(value)
+			if err != nil {
+				return opts, nil, fmt.Errorf("invalid length %q", value)
+			}
+			opts.length = n
+		default:
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return opts, nil, fmt.Errorf("invalid number of lines %q", value)
+			}
+			opts.lines = n
+		}
+	}
+	return opts, paths, nil
+}
+
+// catFiles writes remote files to stdout: whole or the range given by
+// --offset and --length for cat, their first or last lines for head and
+// tail. Like head and tail, a header names each file when there are several.
+func catFiles(ctx context.Context, client *filebrowser.Client, cmd string, opts catOptions, paths []string) error {
+	out := bufio.NewWriter(os.Stdout)
+	defer func() {
+		_ = out.Flush()
+	}()
+	for i, p := range paths {
+		if cmd != "cat" && len(paths) > 1 {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			fmt.Fprintf(out, "==> %s <==\n", p)
+		}
+		var err error
+		switch cmd {
+		case "head":
+			err = headFile(ctx, client, out, p, opts.lines)
+		case "tail":
+			err = tailFile(ctx, client, out, p, opts.lines)
+		default:
+			err = catFile(ctx, client, out, p, opts.offset, opts.length)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+func catFile(ctx context.Context, client *filebrowser.Client, out io.Writer, remotePath string, offset, length int64) error {
+	r, _, err := client.Open(ctx, remotePath, offset, length)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("failed to read %s: %w", remotePath, err)
+	}
+	return nil
+}
+
+// headFile writes the first n lines of a remote file, stopping the transfer
+// once it has them
+func headFile(ctx context.Context, client *filebrowser.Client, out io.Writer, remotePath string, n int) error {
+	if n == 0 {
+		return nil
+	}
+	r, _, err := client.Open(ctx, remotePath, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	br := bufio.NewReader(r)
+	for lines := 0; lines < n; {
+		line, err := br.ReadSlice('\n')
+		if _, werr := out.Write(line); werr != nil {
+			return werr
+		}
+		switch err {
+		case nil:
+			lines++
+		case bufio.ErrBufferFull:
+			// The rest of a long line follows
+		case io.EOF:
+			return nil
+		default:
+			return fmt.Errorf("failed to read %s: %w", remotePath, err)
+		}
+	}
+	return nil
+}
+
+// tailFile writes the last n lines of a remote file, fetching no more of its
+// end than it takes to find them
+func tailFile(ctx context.Context, client *filebrowser.Client, out io.Writer, remotePath string, n int) error {
+	for chunk := int64(tailChunk); ; chunk *= 4 {
+		r, size, err := client.Open(ctx, remotePath, -chunk, -1)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", remotePath, err)
+		}
+		whole := int64(len(data)) < chunk || size >= 0 && int64(len(data)) >= size
+		if start, ok := lastLines(data, n); ok || whole {
+			_, err := out.Write(data[start:])
+			return err
+		}
+	}
+}
+
+// lastLines returns where the last n lines of data start, or false if data
+// holds fewer lines
+func lastLines(data []byte, n int) (int, bool) {
+	if n == 0 {
+		return len(data), true
+	}
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+	for i := end - 1; i >= 0; i-- {
+		if data[i] == '\n' {
+			if n--; n == 0 {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,692 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// configFile is the parsed contents of config.toml. Top-level keys are
+// defaults shared by every profile; [profiles.<name>] tables override them.
+//
+//	default_profile = "work"
+//	timeout = "30s"
+//
+//	[profiles.work]
+//	url = "https://files.example.com"
+//	username = "alice"
+//	ignore = '\.git$'
+type configFile struct {
+	Path     string
+	Defaults map[string]string
+	Profiles map[string]map[string]string
+}
+
+// defaultRetries is how often fbcli retries a request that failed with a
+// transient error unless configured otherwise
+const defaultRetries = 3
+
+// defaultChunkThreshold is the file size from which fbcli uses resumable
+// chunked uploads unless configured otherwise
+const defaultChunkThreshold = 10 << 20
+
+// profileKeys lists the settings a profile (or the top level) may contain
+var profileKeys = map[string]bool{
+	"url":              true,
+	"username":         true,
+	"password":         true,
+	"password_file":    true,
+	"password_command": true,
+	"ignore":           true,
+	"timeout":          true,
+	"retries":          true,
+	"jobs":             true,
+	"chunk_size":       true,
+	"chunk_threshold":  true,
+	"cacert":           true,
+	"cert":             true,
+	"key":              true,
+	"insecure":         true,
+	"proxy":            true,
+	"auth_method":      true,
+	"auth_header":      true,
+	"output":           true,
+}
+
+// defaultConfigPath returns ~/.config/fbcli/config.toml (or the platform equivalent)
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "fbcli", "config.toml")
+}
+
+// loadConfigFile reads and parses a config file. A missing file yields an
+// empty configuration unless mustExist is set.
+func loadConfigFile(path string, mustExist bool) (*configFile, error) {
+	cf := &configFile{Path: path, Defaults: map[string]string{}, Profiles: map[string]map[string]string{}}
+	if path == "" {
+		return cf, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !mustExist {
+			return cf, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	section := cf.Defaults
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name, err := parseProfileHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+			}
+			if _, ok := cf.Profiles[name]; !ok {
+				cf.Profiles[name] = map[string]string{}
+			}
+			section = cf.Profiles[name]
+			continue
+		}
+		key, value, err := parseKeyValue(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+		if key == "default_profile" {
+			if len(cf.Profiles) > 0 {
+				return nil, fmt.Errorf("%s:%d: default_profile must appear before any profile table", path, lineNo)
+			}
+		} else if !profileKeys[key] {
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, lineNo, key)
+		}
+		section[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// parseProfileHeader parses a table header like [profiles.work] or [profiles."my box"]
+func parseProfileHeader(line string) (string, error) {
+	if !strings.HasSuffix(line, "]") {
+		return "", fmt.Errorf("malformed table header %s", line)
+	}
+	inner := strings.TrimSpace(line[1 : len(line)-1])
+	if !strings.HasPrefix(inner, "profiles.") {
+		return "", fmt.Errorf("unknown table %s (expected [profiles.<name>])", line)
+	}
+	name := strings.TrimSpace(strings.TrimPrefix(inner, "profiles."))
+	if strings.HasPrefix(name, `"`) || strings.HasPrefix(name, "'") {
+		unquoted, rest, err := parseString(name)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return "", fmt.Errorf("malformed table header %s", line)
+		}
+		name = unquoted
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty profile name in %s", line)
+	}
+	return name, nil
+}
+
+// parseKeyValue parses `key = value` where value is a string, boolean or number
+func parseKeyValue(line string) (string, string, error) {
+	eq := strings.Index(line, "=")
+	if eq < 0 {
+		return "", "", fmt.Errorf("expected key = value")
+	}
+	key := strings.TrimSpace(line[:eq])
+	raw := strings.TrimSpace(line[eq+1:])
+	if key == "" || raw == "" {
+		return "", "", fmt.Errorf("expected key = value")
+	}
+	var value, rest string
+	if raw[0] == '"' || raw[0] == '\'' {
+		var err error
+		value, rest, err = parseString(raw)
+		if err != nil {
+			return "", "", err
+		}
+	} else {
+		value, rest = raw, ""
+		if i := strings.Index(raw, "#"); i >= 0 {
+			value, rest = strings.TrimSpace(raw[:i]), raw[i:]
+		}
+		if value != "true" && value != "false" {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return "", "", fmt.Errorf("invalid value for %s: %s (strings must be quoted)", key, value)
+			}
+		}
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", "", fmt.Errorf("unexpected text after value: %s", rest)
+	}
+	return key, value, nil
+}
+
+// parseString parses a TOML basic ("...") or literal ('...') string at the
+// start of s and returns it along with the remaining text
+func parseString(s string) (string, string, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		ch := s[i]
+		if ch == quote {
+			return b.String(), s[i+1:], nil
+		}
+		if ch == '\\' && quote == '"' {
+			i++
+			if i >= len(s) {
+				break
+			}
+			switch s[i] {
+			case '"', '\\':
+				b.WriteByte(s[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				return "", "", fmt.Errorf("unsupported escape \\%c", s[i])
+			}
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+// setting is a resolved configuration value together with where it came from
+type setting struct {
+	Value  string
+	Source string
+}
+
+// set stores v unless the setting already has a value from a higher-priority source
+func (s *setting) set(v, source string) {
+	if s.Source == "" && v != "" {
+		s.Value, s.Source = v, source
+	}
+}
+
+// settings holds every connection setting resolved from (in priority order)
+// command-line flags, a profile selected with --profile or FBCLI_PROFILE,
+// environment variables, the default_profile, the config file's top-level
+// defaults and finally interactive prompts. FILEBROWSER_PASSWORD is left out
+// when a profile is selected and the URL does not come from the environment.
+type settings struct {
+	ConfigPath string
+	Profile    setting
+	URL        setting
+	Username   setting
+	Password   setting
+	// PasswordFile and PasswordCommand are alternatives to Password that
+	// are only read or run when a login is needed
+	PasswordFile    setting
+	PasswordCommand setting
+	Ignore          setting
+	Timeout         setting
+	Retries         setting
+	Jobs            setting
+	ChunkSize       setting
+	ChunkThreshold  setting
+	CACert          setting
+	Cert            setting
+	Key             setting
+	Insecure        setting
+	Proxy           setting
+	AuthMethod      setting
+	AuthHeader      setting
+	Output          setting
+	// StdinUpload is set when standard input is the data of an upload, so
+	// it cannot also answer prompts or supply the password
+	StdinUpload bool
+}
+
+// globalOptions are the flags accepted by every command
+type globalOptions struct {
+	ConfigPath string
+	Profile    string
+	AuthMethod string
+	AuthHeader string
+
+	PasswordFile string
+
+	Timeout string
+	Retries string
+	Jobs    string
+
+	ChunkSize      string
+	ChunkThreshold string
+
+	CACert   string
+	Cert     string
+	Key      string
+	Insecure string
+	Proxy    string
+
+	Output     string
+	NoProgress bool
+}
+
+// parseGlobalFlags removes the global flags (in either "--flag value" or
+// "--flag=value" form) from the start of args. Parsing stops at the command
+// name, so the arguments of the command are passed on untouched even if they
+// look like global flags.
+func parseGlobalFlags(args []string) (globalOptions, []string, error) {
+	var opts globalOptions
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		var target *string
+		switch name {
+		case "--config":
+			target = &opts.ConfigPath
+		case "--profile":
+			target = &opts.Profile
+		case "--auth-method":
+			target = &opts.AuthMethod
+		case "--auth-header":
+			target = &opts.AuthHeader
+		case "--password-file":
+			target = &opts.PasswordFile
+		case "--timeout":
+			target = &opts.Timeout
+		case "--retries":
+			target = &opts.Retries
+		case "--jobs", "-j":
+			target = &opts.Jobs
+		case "--chunk-size":
+			target = &opts.ChunkSize
+		case "--chunk-threshold":
+			target = &opts.ChunkThreshold
+		case "--cacert":
+			target = &opts.CACert
+		case "--cert":
+			target = &opts.Cert
+		case "--key":
+			target = &opts.Key
+		case "--proxy":
+			target = &opts.Proxy
+		case "--output":
+			target = &opts.Output
+		case "--insecure":
+			// A switch; "--insecure=false" turns a configured insecure off
+			opts.Insecure = "true"
+			if hasValue {
+				opts.Insecure = value
+			}
+			continue
+		case "--no-progress":
+			opts.NoProgress = true
+			continue
+		default:
+			return opts, append(rest, args[i:]...), nil
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		*target = value
+	}
+	return opts, rest, nil
+}
+
+// loadSettings resolves all settings for the given global options
+func loadSettings(opts globalOptions) (*settings, error) {
+	s := &settings{}
+
+	configPath, mustExist := opts.ConfigPath, opts.ConfigPath != ""
+	if !mustExist {
+		if env := os.Getenv("FBCLI_CONFIG"); env != "" {
+			configPath, mustExist = env, true
+		} else {
+			configPath = defaultConfigPath()
+		}
+	}
+	cf, err := loadConfigFile(configPath, mustExist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file: %w", err)
+	}
+	if _, err := os.Stat(configPath); err == nil {
+		s.ConfigPath = configPath
+	}
+
+	s.Profile.set(opts.Profile, "--profile")
+	s.Profile.set(os.Getenv("FBCLI_PROFILE"), "env FBCLI_PROFILE")
+	s.Profile.set(cf.Defaults["default_profile"], "default_profile")
+
+	s.AuthMethod.set(opts.AuthMethod, "--auth-method")
+	s.AuthHeader.set(opts.AuthHeader, "--auth-header")
+	s.Timeout.set(opts.Timeout, "--timeout")
+	s.Retries.set(opts.Retries, "--retries")
+	s.Jobs.set(opts.Jobs, "--jobs")
+	s.ChunkSize.set(opts.ChunkSize, "--chunk-size")
+	s.ChunkThreshold.set(opts.ChunkThreshold, "--chunk-threshold")
+	s.CACert.set(opts.CACert, "--cacert")
+	s.Cert.set(opts.Cert, "--cert")
+	s.Key.set(opts.Key, "--key")
+	s.Insecure.set(opts.Insecure, "--insecure")
+	s.Proxy.set(opts.Proxy, "--proxy")
+	s.Output.set(opts.Output, "--output")
+	s.Output.set(os.Getenv("FBCLI_OUTPUT"), "env FBCLI_OUTPUT")
+
+	s.setPassword("", opts.PasswordFile, "", "--password-file")
+
+	// The FILEBROWSER_* variables act as an unnamed profile
+	applyEnv := func() {
+		s.URL.set(os.Getenv("FILEBROWSER_URL"), "env FILEBROWSER_URL")
+		s.Username.set(os.Getenv("FILEBROWSER_USERNAME"), "env FILEBROWSER_USERNAME")
+		// Keep the password away from the server of a profile
+		if s.URL.Source == "env FILEBROWSER_URL" || s.Profile.Value == "" {
+			s.setPassword(os.Getenv("FILEBROWSER_PASSWORD"), "", "", "env FILEBROWSER_PASSWORD")
+		}
+	}
+	apply := func(values map[string]string, source string) {
+		s.URL.set(values["url"], source)
+		s.Username.set(values["username"], source)
+		s.setPassword(values["password"], values["password_file"], values["password_command"], source)
+		s.Ignore.set(values["ignore"], source)
+		s.Timeout.set(values["timeout"], source)
+		s.Retries.set(values["retries"], source)
+		s.Jobs.set(values["jobs"], source)
+		s.ChunkSize.set(values["chunk_size"], source)
+		s.ChunkThreshold.set(values["chunk_threshold"], source)
+		s.CACert.set(values["cacert"], source)
+		s.Cert.set(values["cert"], source)
+		s.Key.set(values["key"], source)
+		s.Insecure.set(values["insecure"], source)
+		s.Proxy.set(values["proxy"], source)
+		s.AuthMethod.set(values["auth_method"], source)
+		s.AuthHeader.set(values["auth_header"], source)
+		s.Output.set(values["output"], source)
+	}
+	var profile map[string]string
+	if name := s.Profile.Value; name != "" {
+		var ok bool
+		profile, ok = cf.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found in %s (available: %s)", name, displayPath(configPath), strings.Join(cf.profileNames(), ", "))
+		}
+	}
+	// A profile chosen with --profile or FBCLI_PROFILE beats the
+	// environment, which in turn beats the default_profile
+	if profile != nil && s.Profile.Source != "default_profile" {
+		apply(profile, "profile "+s.Profile.Value)
+		applyEnv()
+	} else {
+		applyEnv()
+		if profile != nil {
+			apply(profile, "profile "+s.Profile.Value)
+		}
+	}
+	apply(cf.Defaults, "config file")
+
+	switch s.AuthMethod.Value {
+	case "", filebrowser.AuthJSON, filebrowser.AuthProxy, filebrowser.AuthNone:
+	default:
+		return nil, fmt.Errorf("invalid auth method %q (%s): want %s, %s or %s", s.AuthMethod.Value, s.AuthMethod.Source,
+			filebrowser.AuthJSON, filebrowser.AuthProxy, filebrowser.AuthNone)
+	}
+	switch s.Output.Value {
+	case "", "text", "json":
+	default:
+		return nil, fmt.Errorf("invalid output format %q (%s): want text or json", s.Output.Value, s.Output.Source)
+	}
+	return s, nil
+}
+
+// setPassword takes the password, password file and password command from a
+// single source, unless a higher-priority source already supplied any of them
+func (s *settings) setPassword(password, file, command, source string) {
+	if s.Password.Source != "" || s.PasswordFile.Source != "" || s.PasswordCommand.Source != "" {
+		return
+	}
+	s.Password.set(password, source)
+	s.PasswordFile.set(expandHome(file), source)
+	s.PasswordCommand.set(command, source)
+}
+
+// profileNames returns the sorted profile names
+func (cf *configFile) profileNames() []string {
+	names := make([]string, 0, len(cf.Profiles))
+	for name := range cf.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	return names
+}
+
+// clientConfig converts the resolved settings into a client configuration
+func (s *settings) clientConfig() (filebrowser.Config, error) {
+	cfg := filebrowser.Config{
+		URL:        strings.TrimRight(s.URL.Value, "/"),
+		Username:   s.Username.Value,
+		Password:   s.Password.Value,
+		CACert:     expandHome(s.CACert.Value),
+		ClientCert: expandHome(s.Cert.Value),
+		ClientKey:  expandHome(s.Key.Value),
+		Proxy:      s.Proxy.Value,
+		AuthMethod: s.AuthMethod.Value,
+		AuthHeader: s.AuthHeader.Value,
+	}
+	if s.Timeout.Value != "" {
+		d, err := parseTimeout(s.Timeout.Value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid timeout %q (%s): %v", s.Timeout.Value, s.Timeout.Source, err)
+		}
+		cfg.Timeout = d
+	}
+	cfg.Retries = defaultRetries
+	if s.Retries.Value != "" {
+		n, err := strconv.Atoi(s.Retries.Value)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid retries value %q (%s)", s.Retries.Value, s.Retries.Source)
+		}
+		cfg.Retries = n
+	}
+	cfg.Jobs = 1
+	if s.Jobs.Value != "" {
+		n, err := strconv.Atoi(s.Jobs.Value)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid jobs value %q (%s)", s.Jobs.Value, s.Jobs.Source)
+		}
+		cfg.Jobs = n
+	}
+	cfg.ChunkThreshold = defaultChunkThreshold
+	if s.ChunkThreshold.Value != "" {
+		n, err := parseSize(s.ChunkThreshold.Value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid chunk threshold %q (%s): %v", s.ChunkThreshold.Value, s.ChunkThreshold.Source, err)
+		}
+		cfg.ChunkThreshold = n
+	}
+	if s.ChunkSize.Value != "" {
+		n, err := parseSize(s.ChunkSize.Value)
+		if err != nil || n == 0 {
+			return cfg, fmt.Errorf("invalid chunk size %q (%s)", s.ChunkSize.Value, s.ChunkSize.Source)
+		}
+		cfg.ChunkSize = n
+	}
+	if s.Insecure.Value != "" {
+		b, err := strconv.ParseBool(s.Insecure.Value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid insecure value %q (%s)", s.Insecure.Value, s.Insecure.Source)
+		}
+		cfg.Insecure = b
+	}
+	return cfg, nil
+}
+
+// parseSize parses a byte count with an optional binary unit suffix, e.g.
+// "512", "64K", "10M", "1.5GB" or "2GiB"
+func parseSize(v string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(v))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	if s != "" {
+		if i := strings.IndexByte("KMGT", s[len(s)-1]); i >= 0 {
+			multiplier = int64(1) << (10 * (i + 1))
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", v)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// parseTimeout accepts Go durations ("30s", "2m") or a plain number of seconds
+func parseTimeout(v string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	return time.ParseDuration(v)
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(p string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[2:])
+		}
+	}
+	return p
+}
+
+// displayPath abbreviates the home directory in p as ~
+func displayPath(p string) string {
+	if home, err := os.UserHomeDir(); err == nil && home != "" && strings.HasPrefix(p, home+string(os.PathSeparator)) {
+		return "~" + p[len(home):]
+	}
+	return p
+}
+
+// showConfig prints the resolved settings and where each one came from
+func showConfig(s *settings) {
+	fmt.Printf("Version: %s\n", version)
+	if s.ConfigPath != "" {
+		fmt.Printf("Config: %s\n", displayPath(s.ConfigPath))
+	} else {
+		fmt.Printf("Config: (none)\n")
+	}
+	rows := []struct {
+		name string
+		s    setting
+	}{
+		{"Profile", s.Profile},
+		{"URL", s.URL},
+		{"Username", s.Username},
+		{"Password", setting{redactPassword(s.Password.Value), s.Password.Source}},
+		{"Password file", s.PasswordFile},
+		{"Password command", s.PasswordCommand},
+		{"Auth method", s.AuthMethod},
+		{"Auth header", s.AuthHeader},
+		{"Ignore", s.Ignore},
+		{"Timeout", s.Timeout},
+		{"Retries", s.Retries},
+		{"Jobs", s.Jobs},
+		{"Chunk size", s.ChunkSize},
+		{"Chunk threshold", s.ChunkThreshold},
+		{"CA cert", s.CACert},
+		{"Client cert", s.Cert},
+		{"Client key", s.Key},
+		{"Insecure", s.Insecure},
+		{"Proxy", s.Proxy},
+		{"Output", s.Output},
+	}
+	for _, row := range rows {
+		if row.s.Source == "" {
+			if row.name == "Profile" || row.name == "URL" || row.name == "Username" {
+				fmt.Printf("%s: (not set)\n", row.name)
+			}
+			continue
+		}
+		fmt.Printf("%s: %s [%s]\n", row.name, row.s.Value, row.s.Source)
+	}
+}
+
+// showConfigJSON prints the resolved settings as a JSON object keyed by
+// their names in the config file
+func showConfigJSON(s *settings) {
+	type value struct {
+		Value  string `json:"value"`
+		Source string `json:"source"`
+	}
+	values := map[string]value{}
+	for key, v := range map[string]setting{
+		"profile":          s.Profile,
+		"url":              s.URL,
+		"username":         s.Username,
+		"password":         {redactPassword(s.Password.Value), s.Password.Source},
+		"password_file":    s.PasswordFile,
+		"password_command": s.PasswordCommand,
+		"auth_method":      s.AuthMethod,
+		"auth_header":      s.AuthHeader,
+		"ignore":           s.Ignore,
+		"timeout":          s.Timeout,
+		"retries":          s.Retries,
+		"jobs":             s.Jobs,
+		"chunk_size":       s.ChunkSize,
+		"chunk_threshold":  s.ChunkThreshold,
+		"cacert":           s.CACert,
+		"cert":             s.Cert,
+		"key":              s.Key,
+		"insecure":         s.Insecure,
+		"proxy":            s.Proxy,
+		"output":           s.Output,
+	} {
+		if v.Source != "" {
+			values[key] = value{v.Value, v.Source}
+		}
+	}
+	jsonOut.record(struct {
+		Version  string           `json:"version"`
+		Config   string           `json:"config"`
+		Settings map[string]value `json:"settings"`
+	}{version, s.ConfigPath, values})
+}
+
+func redactPassword(s string) string {
+	length := len(s)
+	if length == 0 {
+		return ""
+	}
+	if length == 1 {
+		return "*"
+	}
+	if length == 2 {
+		return "**"
+	}
+	return string(s[0]) + strings.Repeat("*", length-2) + string(s[length-1])
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyValue(t *testing.T) {
+	tests := []struct {
+		line, key, value string
+		wantErr          bool
+	}{
+		{line: `url = "https://files.example.com"`, key: "url", value: "https://files.example.com"},
+		{line: `password = 'C:\secret'`, key: "password", value: `C:\secret`},
+		{line: `username = "a \"quoted\" name"`, key: "username", value: `a "quoted" name`},
+		{line: `ignore = "\\.git$" # skip git`, key: "ignore", value: `\.git$`},
+		{line: `retries = 5`, key: "retries", value: "5"},
+		{line: `insecure = true # lab box`, key: "insecure", value: "true"},
+		{line: `timeout=30`, key: "timeout", value: "30"},
+		{line: `url = https://files.example.com`, wantErr: true},
+		{line: `url = "unterminated`, wantErr: true},
+		{line: `url = "a" "b"`, wantErr: true},
+		{line: `url = "\q"`, wantErr: true},
+		{line: `= "value"`, wantErr: true},
+		{line: `url =`, wantErr: true},
+		{line: `url`, wantErr: true},
+	}
+	for _, tt := range tests {
+		key, value, err := parseKeyValue(tt.line)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseKeyValue(%q) = %q, %q; want an error", tt.line, key, value)
+			}
+			continue
+		}
+		if err != nil || key != tt.key || value != tt.value {
+			t.Errorf("parseKeyValue(%q) = %q, %q, %v; want %q, %q", tt.line, key, value, err, tt.key, tt.value)
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	data := `# fbcli settings
+default_profile = "work"
+timeout = "30s"
+
+[profiles.work]
+url = "https://files.example.com"
+username = "alice"
+
+[profiles."home box"]
+url = "http://nas.local:8080"
+insecure = true
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := loadConfigFile(path, true)
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if got := cf.Defaults["default_profile"]; got != "work" {
+		t.Errorf("default_profile = %q, want work", got)
+	}
+	if got := cf.Defaults["timeout"]; got != "30s" {
+		t.Errorf("timeout = %q, want 30s", got)
+	}
+	if got := cf.Profiles["work"]["username"]; got != "alice" {
+		t.Errorf("work username = %q, want alice", got)
+	}
+	if got := cf.Profiles["home box"]["insecure"]; got != "true" {
+		t.Errorf("home box insecure = %q, want true", got)
+	}
+}
+
+func TestLoadConfigFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.toml")
+	cf, err := loadConfigFile(path, false)
+	if err != nil || len(cf.Defaults) != 0 || len(cf.Profiles) != 0 {
+		t.Errorf("loadConfigFile(missing, false) = %+v, %v; want an empty config", cf, err)
+	}
+	if _, err := loadConfigFile(path, true); err == nil {
+		t.Error("loadConfigFile(missing, true) succeeded; want an error")
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"unknown key", "colour = \"blue\"\n", `:1: unknown setting "colour"`},
+		{"unknown table", "[servers.work]\n", ":1: unknown table"},
+		{"late default_profile", "[profiles.work]\ndefault_profile = \"work\"\n", ":2: default_profile must appear before any profile table"},
+		{"bad value", "\nurl = files.example.com\n", ":2: invalid value for url"},
+		{"empty profile name", "[profiles.\"\"]\n", ":1: empty profile name"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadConfigFile(path, true)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: loadConfigFile error = %v, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseGlobalFlags(t *testing.T) {
+	opts, rest, err := parseGlobalFlags([]string{"--profile", "work", "--timeout=30s", "-j", "4", "--insecure", "find", "/", "-exec", "echo", "--output", "json", "-j", "{}", ";"})
+	if err != nil {
+		t.Fatalf("parseGlobalFlags: %v", err)
+	}
+	if opts.Profile != "work" || opts.Timeout != "30s" || opts.Jobs != "4" || opts.Insecure != "true" {
+		t.Errorf("parseGlobalFlags options = %+v", opts)
+	}
+	if opts.Output != "" {
+		t.Errorf("Output = %q; flags after the command must be left alone", opts.Output)
+	}
+	want := []string{"find", "/", "-exec", "echo", "--output", "json", "-j", "{}", ";"}
+	if !slices.Equal(rest, want) {
+		t.Errorf("parseGlobalFlags rest = %q, want %q", rest, want)
+	}
+	if _, _, err := parseGlobalFlags([]string{"--profile"}); err == nil {
+		t.Error("parseGlobalFlags(--profile) succeeded; want a missing value error")
+	}
+}
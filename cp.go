@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// copyOptions are the flags of cp and mv
+type copyOptions struct {
+	recursive  bool
+	onConflict filebrowser.Conflict
+}
+
+// parseCopyArgs separates the flags of cp (-r, -f, -n, --auto-rename) or mv
+// (the same but -r) from the paths
+func parseCopyArgs(move bool, args []string) (copyOptions, []string, error) {
+	var opts copyOptions
+	var paths []string
+	conflictFlag := ""
+	for _, arg := range args {
+		onConflict := filebrowser.ConflictFail
+		switch {
+		case !move && (arg == "-r" || arg == "-R" || arg == "--recursive"):
+			opts.recursive = true
+			continue
+		case arg == "-f" || arg == "--force":
+			onConflict = filebrowser.ConflictOverwrite
+		case arg == "-n" || arg == "--no-clobber":
+			onConflict = filebrowser.ConflictSkip
+		case arg == "--auto-rename":
+			onConflict = filebrowser.ConflictRename
+		default:
+			paths = append(paths, arg)
+			continue
+		}
+		if conflictFlag != "" && conflictFlag != arg {
+			return opts, nil, fmt.Errorf("%s and %s cannot be used together", conflictFlag, arg)
+		}
+		conflictFlag, opts.onConflict = arg, onConflict
+	}
+	return opts, paths, nil
+}
+
+// copyPaths copies, or with move moves, remote files and directories on the
+// server: a single source to dst, or into dst if that is a directory; several
+// sources into the directory dst
+func copyPaths(ctx context.Context, client *filebrowser.Client, move bool, opts copyOptions, sources []string, dst string) error {
+	dstIsDir := strings.HasSuffix(dst, "/")
+	if !dstIsDir {
+		isDir, err := client.IsDir(ctx, dst)
+		if err != nil && !errors.Is(err, filebrowser.ErrNotFound) {
+			return err
+		}
+		dstIsDir = isDir
+	}
+	if len(sources) > 1 && !dstIsDir {
+		return fmt.Errorf("target '%s' is not a directory", dst)
+	}
+	for _, src := range sources {
+		if !move {
+			isDir, err := client.IsDir(ctx, src)
+			if err != nil {
+				return err
+			}
+			if isDir && !opts.recursive {
+				return fmt.Errorf("'%s' is a directory (use -r to copy it)", src)
+			}
+		}
+		target := dst
+		if dstIsDir {
+			target = path.Join(dst, path.Base(src))
+		}
+		var err error
+		if move {
+			err = client.Move(ctx, src, target, opts.onConflict)
+		} else {
+			err = client.Copy(ctx, src, target, opts.onConflict)
+		}
+		if errors.Is(err, filebrowser.ErrExists) {
+			return fmt.Errorf("%w (use -f to replace it, -n to skip it or --auto-rename)", err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
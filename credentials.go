@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+	"golang.org/x/term"
+)
+
+// errStdinUpload is returned instead of prompting while standard input is
+// being uploaded
+var errStdinUpload = errors.New("standard input is the data being uploaded")
+
+// stdinUpload reports whether the arguments of cmd upload standard input
+func stdinUpload(cmd string, args []string) bool {
+	switch cmd {
+	case "upload", "up", "put":
+	default:
+		return false
+	}
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-i":
+			i++
+		case args[i] == "-":
+			return true
+		case !strings.HasPrefix(args[i], "-"):
+			return false
+		}
+	}
+	return false
+}
+
+// getCredentials prompts for any connection setting still missing from st
+func getCredentials(st *settings) error {
+	if st.URL.Value == "" {
+		if st.StdinUpload {
+			return fmt.Errorf("cannot ask for the File Browser URL: %w; set FILEBROWSER_URL or use a profile", errStdinUpload)
+		}
+		st.URL.Source = "prompt"
+		fmt.Print("Enter File Browser URL: ")
+		if _, err := fmt.Scanln(&st.URL.Value); err != nil {
+			return err
+		}
+	}
+
+	// ~/.netrc may supply the username and password for the server's host
+	if st.Password.Source == "" && st.PasswordFile.Source == "" && st.PasswordCommand.Source == "" {
+		if entry, ok := lookupNetrc(st.URL.Value, st.Username.Value); ok {
+			source := displayPath(netrcPath())
+			st.Username.set(entry.Login, source)
+			st.Password.set(entry.Password, source)
+		}
+	}
+
+	// Instances without authentication need no username
+	if st.Username.Value == "" && st.AuthMethod.Value != filebrowser.AuthNone {
+		if st.StdinUpload {
+			return fmt.Errorf("cannot ask for the username: %w; set FILEBROWSER_USERNAME or use a profile", errStdinUpload)
+		}
+		st.Username.Source = "prompt"
+		fmt.Print("Enter Username: ")
+		if _, err := fmt.Scanln(&st.Username.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readPassword supplies the password when a login is needed: from the
+// password file or the output of the password command if one is configured,
+// and by prompting for it otherwise
+func readPassword(st *settings) (string, error) {
+	switch {
+	case st.PasswordFile.Value != "":
+		return readPasswordFile(st.PasswordFile.Value)
+	case st.PasswordCommand.Value != "":
+		// The command must not swallow the data being uploaded
+		var stdin io.Reader = os.Stdin
+		if st.StdinUpload {
+			stdin = nil
+		}
+		return runPasswordCommand(st.PasswordCommand.Value, stdin)
+	case st.StdinUpload:
+		return "", fmt.Errorf("cannot ask for the password: %w; set FILEBROWSER_PASSWORD or use --password-file with a file", errStdinUpload)
+	}
+	return promptPassword(st)
+}
+
+// readPasswordFile returns the first line of path ("-" reads stdin). Paths
+// like /dev/fd/3 read the password from an inherited file descriptor.
+func readPasswordFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(io.LimitReader(os.Stdin, 64*1024))
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	password := firstLine(data)
+	if password == "" {
+		return "", fmt.Errorf("password file %s is empty", path)
+	}
+	return password, nil
+}
+
+// runPasswordCommand runs command through the shell and returns the first
+// line of its output, e.g. "pass show filebrowser/work". The command shares
+// the terminal so helpers can ask for a passphrase, and reads from stdin.
+func runPasswordCommand(command string, stdin io.Reader) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdin = stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("password command %q failed: %w", command, err)
+	}
+	password := firstLine(out)
+	if password == "" {
+		return "", fmt.Errorf("password command %q printed no password", command)
+	}
+	return password, nil
+}
+
+// firstLine returns the first line of data without its line ending
+func firstLine(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSuffix(line, "\r")
+}
+
+// promptPassword asks for the password on the terminal (without echoing it)
+// or reads a line from stdin when it is not a terminal. It is only called
+// when a login is actually needed.
+func promptPassword(st *settings) (string, error) {
+	st.Password.Source = "prompt"
+	fmt.Print("Enter Password: ")
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		reader := bufio.NewReader(os.Stdin)
+		password, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		st.Password.Value = strings.TrimSpace(password)
+		return st.Password.Value, nil
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := term.Restore(fd, oldState); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring terminal state: %v\n", err)
+		}
+	}()
+
+	var password []byte
+	var backspace = []byte{' ', ' ', ' '}
+	for {
+		var buf [1]byte
+		n, err := os.Stdin.Read(buf[:])
+		if err != nil || n == 0 {
+			return "", err
+		}
+		char := buf[0]
+		if char == '\r' || char == '\n' {
+			fmt.Print("\r\n")
+			break
+		}
+		switch char {
+		case 8, 127:
+			if len(password) > 0 {
+				password = password[:len(password)-1]
+				if _, err := os.Stdout.Write(backspace); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing backspace: %v\n", err)
+				}
+			}
+		case 3:
+			return "", fmt.Errorf("interrupted")
+		default:
+			password = append(password, char)
+			fmt.Print("*")
+		}
+	}
+	st.Password.Value = string(password)
+	return st.Password.Value, nil
+}
+
+// tokenCachePath returns the token cache file of the active profile,
+// ~/.cache/fbcli/tokens/<profile>.json (or the platform equivalent)
+func tokenCachePath(st *settings) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	name := st.Profile.Value
+	if name == "" {
+		name = "default"
+	}
+	name = regexp.MustCompile(`[^A-Za-z0-9._-]`).ReplaceAllString(name, "_")
+	return filepath.Join(dir, "fbcli", "tokens", name+".json")
+}
+
+// tokenStore returns the on-disk token cache for the active profile, or nil
+// if no cache directory is available
+func tokenStore(st *settings, cfg filebrowser.Config) filebrowser.TokenStore {
+	path := tokenCachePath(st)
+	if path == "" {
+		return nil
+	}
+	return &filebrowser.FileTokenStore{Path: path, URL: cfg.URL, Username: cfg.Username}
+}
+
+// removeCachedToken deletes the token cache of the active profile
+func removeCachedToken(st *settings) error {
+	path := tokenCachePath(st)
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
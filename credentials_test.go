@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestStdinUpload(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		args []string
+		want bool
+	}{
+		{"put", []string{"-", "/backups/db.sql"}, true},
+		{"upload", []string{"--verify", "-", "/logs/dmesg.txt"}, true},
+		{"up", []string{"-i", "-", "./site", "/www"}, false},
+		{"put", []string{"./file.txt", "-"}, false},
+		{"upload", []string{"./file.txt"}, false},
+		{"cat", []string{"-", "/file.txt"}, false},
+	}
+	for _, tt := range tests {
+		if got := stdinUpload(tt.cmd, tt.args); got != tt.want {
+			t.Errorf("stdinUpload(%q, %q) = %v, want %v", tt.cmd, tt.args, got, tt.want)
+		}
+	}
+}
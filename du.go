@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// duOptions are the flags of du
+type duOptions struct {
+	summarize bool
+	human     bool
+	all       bool
+	depth     int // levels below each path to show; -1 for all
+	quota     bool
+}
+
+// usageRecord is the size of a remote file or directory
+type usageRecord struct {
+	Type  string `json:"type"`
+	Path  string `json:"path"`
+	IsDir bool   `json:"isDir"`
+	Size  int64  `json:"size"`
+	Files int    `json:"files"`
+	Dirs  int    `json:"dirs"`
+}
+
+// quotaRecord is the space of the disk holding a remote path
+type quotaRecord struct {
+	Type  string `json:"type"`
+	Path  string `json:"path"`
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+}
+
+// parseDuArgs separates the flags of du (-h, -a, -d, --summarize, --quota)
+// from the remote paths; -s reaches du as the script flag
+func parseDuArgs(args []string) (duOptions, []string, error) {
+	opts := duOptions{depth: -1}
+	var paths []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch {
+		case args[i] == "-h" || args[i] == "--human-readable":
+			opts.human = true
+			continue
+		case args[i] == "-a" || args[i] == "--all":
+			opts.all = true
+			continue
+		case args[i] == "--summarize":
+			opts.summarize = true
+			continue
+		case args[i] == "--quota":
+			opts.quota = true
+			continue
+		case name == "-d" || name == "--max-depth":
+		default:
+			paths = append(paths, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return opts, nil, fmt.Errorf("invalid depth %q", value)
+		}
+		opts.depth = n
+	}
+	// Each path could be on a different disk
+	if opts.quota && len(paths) > 1 {
+		return opts, nil, fmt.Errorf("--quota takes a single path")
+	}
+	return opts, paths, nil
+}
+
+// diskUsage prints how much space remote directories take, adding up the
+// sizes of the files below them, largest first: each path and the
+// directories below it down to opts.depth levels (with -a, files too), or
+// with -s only each path. With --quota, which takes a single path, it also
+// prints the space of the disk holding it.
+func diskUsage(ctx context.Context, client *filebrowser.Client, remotePaths []string, ignoreRegex *regexp.Regexp, opts duOptions) error {
+	if opts.summarize {
+		opts.depth = 0
+	}
+	failed := 0
+	for _, remotePath := range remotePaths {
+		var records []usageRecord
+		isDir, err := client.IsDir(ctx, remotePath)
+		if err != nil {
+			return err
+		}
+		if isDir {
+			nodes, _, err := fetchTree(ctx, client, remotePath, ignoreRegex, treeOptions{}, 1)
+			if err != nil {
+				return err
+			}
+			root := sumUsage(remotePath, nodes, 0, opts, &records)
+			records = append(records, root)
+			walkTree(nodes, func(n *treeNode) {
+				if n.err != nil {
+					failed++
+					warnList(n)
+				}
+			})
+		} else {
+			item, err := client.Stat(ctx, remotePath)
+			if err != nil {
+				return err
+			}
+			records = append(records, usageRecord{Path: remotePath, Size: item.Size, Files: 1})
+		}
+		sort.Slice(records, func(i, j int) bool {
+			if records[i].Size == records[j].Size {
+				return records[i].Path < records[j].Path
+			}
+			return records[i].Size > records[j].Size
+		})
+		for _, rec := range records {
+			if jsonOut != nil {
+				rec.Type = "usage"
+				jsonOut.record(rec)
+			} else if opts.human {
+				fmt.Printf("%-10s %s\n", formatBytes(rec.Size), rec.Path)
+			} else {
+				fmt.Printf("%-12d %s\n", rec.Size, rec.Path)
+			}
+		}
+	}
+	if opts.quota {
+		usage, err := client.Usage(ctx, remotePaths[0])
+		if err != nil {
+			return err
+		}
+		if jsonOut != nil {
+			jsonOut.record(quotaRecord{Type: "quota", Path: remotePaths[0], Total: usage.Total, Used: usage.Used})
+		} else {
+			percent := 0.0
+			if usage.Total > 0 {
+				percent = float64(usage.Used) * 100 / float64(usage.Total)
+			}
+			fmt.Printf("\nDisk usage: %s of %s used (%.1f%%), %s free\n", formatBytes(int64(usage.Used)), formatBytes(int64(usage.Total)),
+				percent, formatBytes(int64(usage.Total-min(usage.Used, usage.Total))))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s could not be listed; sizes are incomplete", plural(failed, "directory", "directories"))
+	}
+	return nil
+}
+
+// sumUsage adds up the entries of dir, which is level levels below the path
+// du was given, and appends the records to show for its subdirectories (and
+// files with -a) to records
+func sumUsage(dir string, nodes []*treeNode, level int, opts duOptions, records *[]usageRecord) usageRecord {
+	total := usageRecord{Path: dir, IsDir: true}
+	show := opts.depth < 0 || level < opts.depth
+	for _, n := range nodes {
+		if !n.item.IsDir {
+			total.Size += n.item.Size
+			total.Files++
+			if opts.all && show {
+				*records = append(*records, usageRecord{Path: path.Join(dir, n.item.Name), Size: n.item.Size, Files: 1})
+			}
+			continue
+		}
+		sub := sumUsage(n.path, n.children, level+1, opts, records)
+		total.Size += sub.Size
+		total.Files += sub.Files
+		total.Dirs += sub.Dirs + 1
+		if show {
+			*records = append(*records, sub)
+		}
+	}
+	return total
+}
@@ -0,0 +1,308 @@
+package filebrowser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TokenStore persists the session token between runs.
+type TokenStore interface {
+	// LoadToken returns the stored token, or "" if there is none.
+	LoadToken() (string, error)
+	// SaveToken stores token, replacing any previous one.
+	SaveToken(token string) error
+}
+
+// Authenticate makes the client ready for requests. It reuses the current
+// token or the one in c.TokenStore while it is valid, renews it through
+// /api/renew when it is about to expire, and logs in otherwise.
+func (c *Client) Authenticate(ctx context.Context) error {
+	if c.currentToken() == "" && c.TokenStore != nil {
+		token, err := c.TokenStore.LoadToken()
+		if err != nil {
+			c.warnf(ctx, "Warning: ignoring token cache: %v\n", err)
+		}
+		c.setToken(token)
+	}
+	if token := c.currentToken(); token != "" && tokenValid(token, time.Now()) {
+		if err := c.renewIfExpiring(ctx); err == nil {
+			return nil
+		}
+	}
+	return c.Login(ctx)
+}
+
+// Login authenticates with the configured credentials and stores the JWT
+// in c.Token (and c.TokenStore) for subsequent requests.
+func (c *Client) Login(ctx context.Context) error {
+	body, err := c.loginBody()
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	resp, err := c.doRequest(ctx, "POST", "/api/login", bytes.NewReader(body), headers, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		// File Browser answers 403 both for wrong credentials and for a
+		// missing reCAPTCHA response; its login page tells them apart.
+		if c.authMethod() == AuthJSON && c.captchaRequired(ctx) {
+			return fmt.Errorf("user '%s': %w", c.Config.Username, ErrCaptchaRequired)
+		}
+		return fmt.Errorf("user '%s': %w", c.Config.Username, ErrBadCredentials)
+	default:
+		return fmt.Errorf("login failed: %w", newAPIError(resp))
+	}
+	// The response body is the JWT token (as in filebrowser_client.sh)
+	b, _ := io.ReadAll(resp.Body)
+	c.storeToken(ctx, strings.TrimSpace(string(b)))
+	return nil
+}
+
+// loginBody returns the /api/login request body for the configured
+// authentication method, asking PasswordFunc for a missing password.
+func (c *Client) loginBody() ([]byte, error) {
+	switch c.authMethod() {
+	case AuthJSON:
+		password := c.Config.Password
+		if password == "" && c.PasswordFunc != nil {
+			var err error
+			if password, err = c.PasswordFunc(); err != nil {
+				return nil, err
+			}
+			c.Config.Password = password
+		}
+		return json.Marshal(struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}{c.Config.Username, password})
+	case AuthProxy, AuthNone:
+		// The server takes the user from the proxy header (see doRequest)
+		// or logs in its only user; the body is not looked at.
+		return []byte("{}"), nil
+	default:
+		return nil, fmt.Errorf("unknown authentication method %q (want %s, %s or %s)",
+			c.Config.AuthMethod, AuthJSON, AuthProxy, AuthNone)
+	}
+}
+
+// authMethod returns the configured authentication method, defaulting to AuthJSON
+func (c *Client) authMethod() string {
+	if c.Config.AuthMethod == "" {
+		return AuthJSON
+	}
+	return c.Config.AuthMethod
+}
+
+// authHeader returns the header carrying the username for AuthProxy
+func (c *Client) authHeader() string {
+	if c.Config.AuthHeader == "" {
+		return DefaultAuthHeader
+	}
+	return c.Config.AuthHeader
+}
+
+// captchaRequired reports whether the server's login page is configured to
+// require a reCAPTCHA, which it announces in its embedded settings.
+func (c *Client) captchaRequired(ctx context.Context) bool {
+	resp, err := c.doRequest(ctx, "GET", "/login", nil, nil, "")
+	if err != nil {
+		return false
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	page, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(page, []byte(`"ReCaptcha":true`))
+}
+
+// Renew exchanges the current token for a fresh one via /api/renew.
+func (c *Client) Renew(ctx context.Context) error {
+	resp, err := c.doRequest(ctx, "POST", "/api/renew", nil, nil, c.currentToken())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("token renewal failed: %w", newAPIError(resp))
+	}
+	b, _ := io.ReadAll(resp.Body)
+	c.storeToken(ctx, strings.TrimSpace(string(b)))
+	return nil
+}
+
+// renewIfExpiring renews the token once less than a quarter of its lifetime
+// is left, so long-running operations never send an expired token.
+func (c *Client) renewIfExpiring(ctx context.Context) error {
+	c.renewMu.Lock()
+	defer c.renewMu.Unlock()
+	token := c.currentToken()
+	claims, ok := parseToken(token)
+	if !ok || claims.Exp == 0 {
+		return nil
+	}
+	exp := time.Unix(claims.Exp, 0)
+	window := 5 * time.Minute
+	if claims.Iat != 0 && claims.Iat < claims.Exp {
+		window = time.Duration(claims.Exp-claims.Iat) * time.Second / 4
+	}
+	if time.Until(exp) > window {
+		return nil
+	}
+	if !exp.After(time.Now()) {
+		// Too late to renew, a fresh login is needed
+		return c.Login(ctx)
+	}
+	return c.Renew(ctx)
+}
+
+// relogin logs in again unless another request already replaced staleToken
+func (c *Client) relogin(ctx context.Context, staleToken string) error {
+	c.renewMu.Lock()
+	defer c.renewMu.Unlock()
+	if c.currentToken() != staleToken {
+		return nil
+	}
+	return c.Login(ctx)
+}
+
+func (c *Client) currentToken() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.Token
+}
+
+func (c *Client) setToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.Token = token
+}
+
+// storeToken sets the token and persists it to c.TokenStore
+func (c *Client) storeToken(ctx context.Context, token string) {
+	c.setToken(token)
+	if c.TokenStore != nil {
+		if err := c.TokenStore.SaveToken(token); err != nil {
+			c.warnf(ctx, "Warning: failed to cache token: %v\n", err)
+		}
+	}
+}
+
+// tokenClaims are the JWT claims fbcli cares about
+type tokenClaims struct {
+	Exp int64 `json:"exp"`
+	Iat int64 `json:"iat"`
+}
+
+// parseToken decodes (without verifying) the claims of a JWT
+func parseToken(token string) (tokenClaims, bool) {
+	var claims tokenClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims, false
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, false
+	}
+	return claims, true
+}
+
+// tokenValid reports whether token is a JWT that has not expired at now.
+// Tokens without an expiry claim are treated as valid.
+func tokenValid(token string, now time.Time) bool {
+	claims, ok := parseToken(token)
+	if !ok {
+		return false
+	}
+	return claims.Exp == 0 || now.Before(time.Unix(claims.Exp, 0))
+}
+
+// FileTokenStore keeps the token in a file readable only by the current
+// user. The server URL and username are stored alongside the token so a
+// token is never reused against a different server or account.
+type FileTokenStore struct {
+	Path     string
+	URL      string
+	Username string
+}
+
+type tokenFile struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+// LoadToken implements TokenStore.
+func (s *FileTokenStore) LoadToken() (string, error) {
+	b, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var tf tokenFile
+	if err := json.Unmarshal(b, &tf); err != nil {
+		return "", fmt.Errorf("corrupt token cache %s: %w", s.Path, err)
+	}
+	if tf.URL != s.URL || tf.Username != s.Username {
+		return "", nil
+	}
+	return tf.Token, nil
+}
+
+// SaveToken implements TokenStore. The file is replaced atomically and
+// created with mode 0600.
+func (s *FileTokenStore) SaveToken(token string) error {
+	b, err := json.Marshal(tokenFile{URL: s.URL, Username: s.Username, Token: token})
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.Path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".token-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if err := tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
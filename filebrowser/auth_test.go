@@ -0,0 +1,64 @@
+package filebrowser
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// makeToken builds an unsigned JWT carrying payload
+func makeToken(payload string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString([]byte(payload)) + ".sig"
+}
+
+func TestParseToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		claims tokenClaims
+		ok     bool
+	}{
+		{"claims", makeToken(`{"exp":1700003600,"iat":1700000000,"user":{"id":1}}`), tokenClaims{Exp: 1700003600, Iat: 1700000000}, true},
+		{"no expiry", makeToken(`{"iat":1700000000}`), tokenClaims{Iat: 1700000000}, true},
+		{"two parts", "abc.def", tokenClaims{}, false},
+		{"not base64", "abc.!!!.def", tokenClaims{}, false},
+		{"not JSON", "abc." + base64.RawURLEncoding.EncodeToString([]byte("nope")) + ".def", tokenClaims{}, false},
+		{"empty", "", tokenClaims{}, false},
+	}
+	for _, tt := range tests {
+		claims, ok := parseToken(tt.token)
+		if ok != tt.ok || (ok && claims != tt.claims) {
+			t.Errorf("%s: parseToken = %+v, %v; want %+v, %v", tt.name, claims, ok, tt.claims, tt.ok)
+		}
+	}
+}
+
+func TestParseTokenPadding(t *testing.T) {
+	// Some servers pad the base64 segments
+	payload := base64.URLEncoding.EncodeToString([]byte(`{"exp":42}`))
+	claims, ok := parseToken("abc." + payload + ".def")
+	if !ok || claims.Exp != 42 {
+		t.Errorf("parseToken(padded) = %+v, %v; want exp 42", claims, ok)
+	}
+}
+
+func TestTokenValid(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"not expired", makeToken(`{"exp":1700000060}`), true},
+		{"expired", makeToken(`{"exp":1699999999}`), false},
+		{"expires now", makeToken(`{"exp":1700000000}`), false},
+		{"no expiry", makeToken(`{}`), true},
+		{"malformed", "garbage", false},
+	}
+	for _, tt := range tests {
+		if got := tokenValid(tt.token, now); got != tt.want {
+			t.Errorf("%s: tokenValid = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+// Package filebrowser implements a client for the File Browser HTTP API
+// (https://filebrowser.org). Every operation reports failures as error values
+// so the package can be embedded in other programs; the fbcli command is a
+// thin layer on top of it.
+package filebrowser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserAgent is sent with every request.
+const UserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36"
+
+// Authentication methods, matching File Browser's auth.method setting.
+const (
+	// AuthJSON logs in with username and password (the default).
+	AuthJSON = "json"
+	// AuthProxy relies on a reverse proxy that authenticates the user and
+	// passes the username in a trusted header.
+	AuthProxy = "proxy"
+	// AuthNone is for instances that run without authentication.
+	AuthNone = "noauth"
+)
+
+// DefaultAuthHeader is the header carrying the username for AuthProxy.
+const DefaultAuthHeader = "X-Remote-User"
+
+var (
+	// ErrNotFound is matched (via errors.Is) by API errors with status 404.
+	ErrNotFound = errors.New("not found")
+	// ErrBadCredentials is returned by Login when the server rejects the
+	// username or password.
+	ErrBadCredentials = errors.New("invalid username or password")
+	// ErrCaptchaRequired is returned by Login when the server only accepts
+	// logins that solved a reCAPTCHA, which this client cannot do.
+	ErrCaptchaRequired = errors.New("server requires a reCAPTCHA to log in")
+	// ErrUnreachable is matched by UnreachableError.
+	ErrUnreachable = errors.New("server unreachable")
+	// ErrExists is returned by Rename and Copy when the destination exists.
+	ErrExists = errors.New("already exists")
+	// ErrChecksumMismatch is returned for a transferred file whose checksum
+	// differs on both sides when Config.Verify is set.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// Config holds the connection settings for a File Browser instance.
+type Config struct {
+	URL      string
+	Username string
+	Password string
+
+	// AuthMethod is AuthJSON (used when empty), AuthProxy or AuthNone.
+	AuthMethod string
+	// AuthHeader is the header that carries Username for AuthProxy; it
+	// defaults to DefaultAuthHeader.
+	AuthHeader string
+
+	// Timeout bounds connecting to the server and waiting for each response
+	// to start. Zero means no limit. Transfers themselves are only bounded by
+	// the context passed to each operation.
+	Timeout time.Duration
+
+	// Retries is how many times a request failing with a transient error
+	// (connection failure, 429, 502, 503 or 504) is repeated, waiting with
+	// exponential backoff or as long as the server asks in Retry-After.
+	// Downloads and chunked uploads instead resume where they stopped, up
+	// to Retries times in a row without progress. Zero disables retries.
+	Retries int
+
+	// ChunkThreshold is the file size from which uploads go through File
+	// Browser's resumable upload (TUS) endpoint in chunks of ChunkSize
+	// bytes, so an interrupted upload can continue where it stopped. Zero
+	// sends every file in a single request.
+	ChunkThreshold int64
+	// ChunkSize defaults to DefaultChunkSize.
+	ChunkSize int64
+
+	// Jobs is how many files Upload, Download, SyncTo and SyncFrom transfer
+	// at the same time. Their output stays in the order of the files either
+	// way. Values below 2 transfer one file at a time.
+	Jobs int
+
+	// DryRun makes Upload, SyncTo, SyncFrom, Mkdir, Delete and
+	// DeleteIgnore only report the changes they would make, each with the
+	// reason, as "Would ..." lines on Out. Nothing is written on either side.
+	DryRun bool
+
+	// Verify makes Upload, UploadReader, Download, SyncTo and SyncFrom
+	// compare the SHA-256 checksum of every file they transfer with the
+	// server's once it is transferred. A file that does not match fails with
+	// an error matching ErrChecksumMismatch; a download is not put in place.
+	Verify bool
+
+	// CACert is a PEM file with additional certificate authorities to trust.
+	CACert string
+	// ClientCert and ClientKey are PEM files with a client certificate to present.
+	ClientCert string
+	ClientKey  string
+	// Insecure disables verification of the server certificate.
+	Insecure bool
+	// Proxy is the URL of an HTTP(S) proxy to use. When empty the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+	Proxy string
+}
+
+// Client talks to a single File Browser instance.
+//
+// Long-running operations report what they are doing to Out and report
+// per-item failures they recover from to Err. Either may be nil, in which
+// case the messages are discarded.
+type Client struct {
+	Config Config
+	// Token is the current session token (JWT). It may be preset to reuse a
+	// session; Authenticate and Login fill it in.
+	Token string
+	// TokenStore, if set, persists the token between runs.
+	TokenStore TokenStore
+	// PasswordFunc, if set, supplies the password when a login is needed and
+	// Config.Password is empty.
+	PasswordFunc func() (string, error)
+	Out          io.Writer
+	Err          io.Writer
+	// Progress, if set, is told about every file Upload, Download,
+	// DownloadZip, SyncTo and SyncFrom transfer or skip. It is called from
+	// the goroutines doing the transfers, so it must be safe for concurrent
+	// use, and should return quickly.
+	Progress func(ProgressEvent)
+	// Changed, if set, is told about every change an operation makes to
+	// either side apart from file transfers, and in a dry run about every
+	// change it would make. Like Progress it may be called concurrently.
+	Changed func(Change)
+
+	tokenMu  sync.Mutex
+	renewMu  sync.Mutex
+	httpOnce sync.Once
+	http     *http.Client
+	httpErr  error
+	retryMu  sync.Mutex
+	retried  []RetriedRequest
+}
+
+// New returns a client for cfg. Call Authenticate (or Login) before any
+// other operation.
+//
+// Every operation takes a context; cancelling it aborts in-flight requests
+// and transfers. Files are downloaded to "<name>.part" and only renamed into
+// place once complete; a later download of the same file resumes from it.
+func New(cfg Config) *Client {
+	return &Client{Config: cfg}
+}
+
+// APIError is returned when the server answers with an unexpected status.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// Is reports whether the error matches target; a 404 matches ErrNotFound.
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// newAPIError consumes the response body and wraps it with the status code.
+func newAPIError(resp *http.Response) *APIError {
+	b, _ := io.ReadAll(resp.Body)
+	return &APIError{StatusCode: resp.StatusCode, Body: string(b)}
+}
+
+// UnreachableError is returned when a request got no HTTP response at all,
+// e.g. because the host name does not resolve, the connection is refused or
+// the TLS handshake fails.
+type UnreachableError struct {
+	URL string
+	Err error
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("cannot reach %s: %v", e.URL, e.Err)
+}
+
+func (e *UnreachableError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches target; it always matches ErrUnreachable.
+func (e *UnreachableError) Is(target error) bool {
+	return target == ErrUnreachable
+}
+
+// logf writes a progress message to c.Out, or to the output buffer of the
+// task running in ctx. A dry run only prints its plan, see planf.
+func (c *Client) logf(ctx context.Context, format string, args ...interface{}) {
+	if !c.Config.DryRun {
+		c.planf(ctx, format, args...)
+	}
+}
+
+// planf writes a change a dry run would make like logf
+func (c *Client) planf(ctx context.Context, format string, args ...interface{}) {
+	if out, ok := ctx.Value(taskOutputKey{}).(*taskOutput); ok {
+		out.add(false, fmt.Sprintf(format, args...))
+	} else if c.Out != nil {
+		fmt.Fprintf(c.Out, format, args...)
+	}
+}
+
+// warnf writes a recoverable error message to c.Err, or to the output buffer
+// of the task running in ctx
+func (c *Client) warnf(ctx context.Context, format string, args ...interface{}) {
+	if out, ok := ctx.Value(taskOutputKey{}).(*taskOutput); ok {
+		out.add(true, fmt.Sprintf(format, args...))
+	} else if c.Err != nil {
+		fmt.Fprintf(c.Err, format, args...)
+	}
+}
+
+// sendFunc sends a request with the given token
+type sendFunc func(ctx context.Context, method, path string, body io.Reader, headers map[string]string, token string) (*http.Response, error)
+
+func (c *Client) apiRequest(ctx context.Context, method, path string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	return c.authRequest(ctx, c.doRequest, method, path, body, headers)
+}
+
+// apiAttempt is apiRequest without the retries of transient failures, for
+// transfers that resume by themselves after a failed attempt. A transient
+// failure, whether a connection error or a response like 503, is returned
+// as an *attemptError.
+func (c *Client) apiAttempt(ctx context.Context, method, path string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	resp, err := c.authRequest(ctx, c.roundTrip, method, path, body, headers)
+	failure := transientFailure(method, resp, err)
+	if failure == nil {
+		return resp, err
+	}
+	var wait time.Duration
+	if resp != nil {
+		wait, _ = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		_ = resp.Body.Close()
+	}
+	return nil, &attemptError{err: failure, wait: min(wait, maxRetryAfter)}
+}
+
+// authRequest sends a request with send, logging in again once if the
+// session expired
+func (c *Client) authRequest(ctx context.Context, send sendFunc, method, path string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	// A failed renewal surfaces as a 401 below, which triggers a fresh login
+	_ = c.renewIfExpiring(ctx)
+	token := c.currentToken()
+	resp, err := send(ctx, method, path, body, headers, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !rewindable(body) {
+		return resp, err
+	}
+
+	// The session expired or was revoked: log in again and retry once
+	_ = resp.Body.Close()
+	if err := c.relogin(ctx, token); err != nil {
+		return nil, fmt.Errorf("session expired and re-login failed: %w", err)
+	}
+	if err := rewind(body); err != nil {
+		return nil, err
+	}
+	return send(ctx, method, path, body, headers, c.currentToken())
+}
+
+// doRequest sends a request, retrying transient failures up to
+// c.Config.Retries times as long as the body can be rewound.
+func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader, headers map[string]string, token string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.roundTrip(ctx, method, path, body, headers, token)
+		failure := transientFailure(method, resp, err)
+		if failure == nil || attempt > c.Config.Retries || !rewindable(body) {
+			if attempt > 1 {
+				c.recordRetry(method, path, attempt, failure)
+			}
+			return resp, err
+		}
+		delay := retryDelay(resp, attempt)
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+		c.warnf(ctx, "%s %s failed (%v), retrying in %s (attempt %d of %d)\n",
+			method, path, failure, formatDelay(delay), attempt+1, c.Config.Retries+1)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+		if err := rewind(body); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// roundTrip makes a single attempt at a request
+func (c *Client) roundTrip(ctx context.Context, method, path string, body io.Reader, headers map[string]string, token string) (*http.Response, error) {
+	if _, ok := body.(io.Closer); ok {
+		// The transport closes the body it sent; keep files open so they
+		// can be rewound for another attempt
+		body = io.NopCloser(body)
+	}
+	u, _ := url.Parse(c.Config.URL + path)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "*/*")
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: "auth", Value: token})
+		req.Header.Set("X-Auth", token)
+	}
+	if c.authMethod() == AuthProxy {
+		req.Header.Set(c.authHeader(), c.Config.Username)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if sized, ok := body.(interface{ Size() int64 }); ok && req.ContentLength == 0 {
+		// Chunks of a file; proxies may insist on a Content-Length
+		req.ContentLength = sized.Size()
+	}
+	client, err := c.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil && ctx.Err() == nil {
+		// Report the underlying network error without the method and URL
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, &UnreachableError{URL: c.Config.URL, Err: err}
+	}
+	if resp != nil {
+		resp.Body = drainingBody{resp.Body}
+	}
+	return resp, err
+}
+
+// rewindable reports whether a request body can be sent a second time
+func rewindable(body io.Reader) bool {
+	if body == nil {
+		return true
+	}
+	_, ok := body.(io.Seeker)
+	return ok
+}
+
+// rewind seeks a rewindable request body back to its start
+func rewind(body io.Reader) error {
+	if s, ok := body.(io.Seeker); ok {
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind request body: %w", err)
+		}
+	}
+	return nil
+}
+
+// encodePathPreserveSlash encodes a path, preserving slashes (for read ops)
+func encodePathPreserveSlash(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// encodeSegments encodes each segment for write operations (mkdir, upload, delete, rename)
+func encodeSegments(p string) string {
+	trimmed := strings.Trim(p, "/") // Only trim slashes, not whitespace
+	if trimmed == "" {
+		return ""
+	}
+	parts := strings.Split(trimmed, "/")
+	for i, seg := range parts {
+		parts[i] = url.PathEscape(seg)
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// ignored reports whether name matches the (optional) ignore regex
+func ignored(name string, ignore *regexp.Regexp) bool {
+	return ignore != nil && ignore.MatchString(name)
+}
+
+// ignoredPath reports whether any segment of a slash-separated relative path
+// matches the ignore regex
+func ignoredPath(relPath string, ignore *regexp.Regexp) bool {
+	if ignore == nil {
+		return false
+	}
+	for _, part := range strings.Split(relPath, "/") {
+		if ignore.MatchString(part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package filebrowser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// BulkError is returned by operations on many files (Upload and Download of
+// directories, SyncTo and SyncFrom) when some of the items failed. The other
+// items were still processed, and each failure was reported to Client.Err
+// as it happened.
+type BulkError struct {
+	// Total is the number of items the operation processed.
+	Total int
+	Errs  []error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d of %d items failed", len(e.Errs), e.Total)
+}
+
+func (e *BulkError) Unwrap() []error {
+	return e.Errs
+}
+
+// taskOutputKey is the context key of the output buffer of a running task
+type taskOutputKey struct{}
+
+type outputEntry struct {
+	toErr bool
+	text  string
+}
+
+// taskOutput buffers the messages of one task until it is its turn to
+// write them
+type taskOutput struct {
+	entries []outputEntry
+	done    bool
+}
+
+func (o *taskOutput) add(toErr bool, text string) {
+	o.entries = append(o.entries, outputEntry{toErr, text})
+}
+
+// taskRunner runs the items of a bulk operation on up to Config.Jobs
+// goroutines. The messages of each item are buffered and written in the
+// order the items were submitted, so output never interleaves, and failed
+// items are collected into a BulkError.
+type taskRunner struct {
+	c     *Client
+	ctx   context.Context
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	pending []*taskOutput
+	total   int
+	errs    []error
+}
+
+func (c *Client) newTaskRunner(ctx context.Context) *taskRunner {
+	return &taskRunner{c: c, ctx: ctx, slots: make(chan struct{}, max(c.Config.Jobs, 1))}
+}
+
+// sequential reports whether items run one at a time, in which case their
+// messages are written directly as they happen
+func (r *taskRunner) sequential() bool {
+	return cap(r.slots) == 1
+}
+
+// run runs fn as the next item, in the background unless items run one at
+// a time. It blocks while all workers are busy.
+func (r *taskRunner) run(fn func(ctx context.Context) error) {
+	if r.sequential() {
+		r.finish(nil, fn(r.ctx))
+		return
+	}
+	out := r.enqueue()
+	select {
+	case r.slots <- struct{}{}:
+	case <-r.ctx.Done():
+		r.finish(out, r.ctx.Err())
+		return
+	}
+	if err := r.ctx.Err(); err != nil {
+		// A slot and the cancellation may have been ready at once
+		<-r.slots
+		r.finish(out, err)
+		return
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		err := fn(context.WithValue(r.ctx, taskOutputKey{}, out))
+		<-r.slots
+		r.finish(out, err)
+	}()
+}
+
+// do runs fn as the next item right away, e.g. to create a directory before
+// the files in it are submitted, and returns its error
+func (r *taskRunner) do(fn func(ctx context.Context) error) error {
+	if r.sequential() {
+		err := fn(r.ctx)
+		r.finish(nil, err)
+		return err
+	}
+	out := r.enqueue()
+	err := fn(context.WithValue(r.ctx, taskOutputKey{}, out))
+	r.finish(out, err)
+	return err
+}
+
+// wait waits for the running items and returns ctx.Err() if the operation
+// was cancelled, or a BulkError if any item failed
+func (r *taskRunner) wait() error {
+	r.wg.Wait()
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.errs) > 0 {
+		return &BulkError{Total: r.total, Errs: append([]error(nil), r.errs...)}
+	}
+	return nil
+}
+
+func (r *taskRunner) enqueue() *taskOutput {
+	out := &taskOutput{}
+	r.mu.Lock()
+	r.pending = append(r.pending, out)
+	r.mu.Unlock()
+	return out
+}
+
+// finish records the outcome of an item and writes the output of all
+// finished items that are no longer waiting for an earlier one
+func (r *taskRunner) finish(out *taskOutput, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total++
+	if err != nil && r.ctx.Err() == nil {
+		r.errs = append(r.errs, err)
+		if out != nil {
+			out.add(true, fmt.Sprintf("Error: %v\n", err))
+		} else {
+			r.c.warnf(r.ctx, "Error: %v\n", err)
+		}
+	}
+	if out == nil {
+		return
+	}
+	out.done = true
+	for len(r.pending) > 0 && r.pending[0].done {
+		for _, e := range r.pending[0].entries {
+			w := r.c.Out
+			if e.toErr {
+				w = r.c.Err
+			}
+			if w != nil {
+				_, _ = io.WriteString(w, e.text)
+			}
+		}
+		r.pending = r.pending[1:]
+	}
+}
@@ -0,0 +1,87 @@
+package filebrowser
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// maxIdleConnsPerHost keeps enough connections alive for concurrent
+// requests to the one server a client talks to
+const maxIdleConnsPerHost = 16
+
+// httpClient returns the HTTP client shared by all requests of c, building
+// it from the TLS, proxy and timeout settings in c.Config on first use. Its
+// transport keeps connections alive, so the many requests of a sync reuse
+// them instead of reconnecting each time.
+func (c *Client) httpClient() (*http.Client, error) {
+	c.httpOnce.Do(func() {
+		c.http, c.httpErr = newHTTPClient(c.Config)
+	})
+	return c.http, c.httpErr
+}
+
+// maxDrain bounds how much of an unread response body is discarded on Close
+const maxDrain = 64 << 10
+
+// drainingBody reads what is left of a (small) response body before closing
+// it; the transport only reuses a connection whose body was read to the end.
+type drainingBody struct {
+	io.ReadCloser
+}
+
+func (b drainingBody) Close() error {
+	_, _ = io.CopyN(io.Discard, b.ReadCloser, maxDrain)
+	return b.ReadCloser.Close()
+}
+
+func newHTTPClient(cfg Config) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.Insecure} // #nosec G402 -- explicit opt-in
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		if cfg.ClientCert == "" || cfg.ClientKey == "" {
+			return nil, fmt.Errorf("a client certificate requires both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConnsPerHost = max(maxIdleConnsPerHost, cfg.Jobs)
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if cfg.Timeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: cfg.Timeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = cfg.Timeout
+		transport.ResponseHeaderTimeout = cfg.Timeout
+	}
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,207 @@
+package filebrowser
+
+import (
+	"io"
+	"sync"
+)
+
+// ProgressKind tells what a ProgressEvent reports.
+type ProgressKind int
+
+const (
+	// Planned announces how many files an operation is going to look at
+	// and their total size, before the first of them starts.
+	Planned ProgressKind = iota
+	// FileStarted is sent when the transfer of a file begins.
+	FileStarted
+	// FileProgress reports how much of a file is in place.
+	FileProgress
+	// FileDone is sent when a file was transferred completely.
+	FileDone
+	// FileSkipped is sent for a file a sync left alone because it is
+	// already up to date.
+	FileSkipped
+	// FileFailed is sent when a file could not be transferred.
+	FileFailed
+)
+
+// ProgressEvent is passed to Client.Progress while files are transferred.
+type ProgressEvent struct {
+	Kind ProgressKind
+	// Path is the remote path of the file; empty for Planned.
+	Path string
+	// Size is the size of the file, or the total size of all files for
+	// Planned; -1 while unknown.
+	Size int64
+	// Files is the number of files for Planned.
+	Files int
+	// Bytes is how much of the file is in place, including what an
+	// interrupted earlier transfer left.
+	Bytes int64
+	// Transferred is how many bytes of the file were sent or received so
+	// far, counting data sent again after a retry.
+	Transferred int64
+	// Err is why the file failed, for FileFailed.
+	Err error
+}
+
+// Change is passed to Client.Changed for every directory created, entry
+// deleted, renamed or copied, and in a dry run for every change that would be
+// made. File transfers that actually run are reported through Client.Progress
+// instead.
+type Change struct {
+	// Action is "mkdir", "upload", "download", "delete", "rename" or "copy".
+	Action string
+	// Path is what the change applies to: the remote file of an upload, the
+	// local file of a download and the new path of a rename or copy.
+	Path string
+	// Source is the file an upload or download copies, the old path of a
+	// rename or the original of a copy.
+	Source string
+	// Local is set when Path is a local path.
+	Local bool
+	// Reason tells why a sync or a dry run makes the change, e.g. "size
+	// differs" or "not in source"; it may be empty.
+	Reason string
+}
+
+// progress passes ev to c.Progress, if set
+func (c *Client) progress(ev ProgressEvent) {
+	if c.Progress != nil {
+		c.Progress(ev)
+	}
+}
+
+// changed passes ch to c.Changed, if set
+func (c *Client) changed(ch Change) {
+	if c.Changed != nil {
+		c.Changed(ch)
+	}
+}
+
+// plan announces the files an operation is about to transfer
+func (c *Client) plan(files int, size int64) {
+	c.progress(ProgressEvent{Kind: Planned, Size: size, Files: files})
+}
+
+// skipFile reports a file that is already in sync
+func (c *Client) skipFile(remotePath string, size int64) {
+	c.progress(ProgressEvent{Kind: FileSkipped, Path: remotePath, Size: size, Bytes: size})
+}
+
+// failFile reports a file that failed before its transfer started
+func (c *Client) failFile(remotePath string, err error) {
+	c.progress(ProgressEvent{Kind: FileFailed, Path: remotePath, Size: -1, Err: err})
+}
+
+// fileProgress tracks the transfer of one file. Uploads are read by the
+// HTTP transport's own goroutine, hence the lock.
+type fileProgress struct {
+	c    *Client
+	path string
+
+	mu          sync.Mutex
+	size        int64
+	bytes       int64
+	transferred int64
+}
+
+// startFile reports that the transfer of remotePath, size bytes long (-1 if
+// unknown), begins
+func (c *Client) startFile(remotePath string, size int64) *fileProgress {
+	p := &fileProgress{c: c, path: remotePath, size: size}
+	c.progress(ProgressEvent{Kind: FileStarted, Path: remotePath, Size: size})
+	return p
+}
+
+// setSize records the size of the file once the server told it
+func (p *fileProgress) setSize(size int64) {
+	p.mu.Lock()
+	p.size = size
+	p.mu.Unlock()
+}
+
+// moved reports that n more bytes went over the wire and pos bytes of the
+// file are now in place
+func (p *fileProgress) moved(n, pos int64) {
+	p.mu.Lock()
+	p.transferred += n
+	p.bytes = pos
+	ev := p.event(FileProgress)
+	p.mu.Unlock()
+	p.c.progress(ev)
+}
+
+// done reports the outcome of the transfer
+func (p *fileProgress) done(err error) {
+	p.mu.Lock()
+	ev := p.event(FileDone)
+	if err != nil {
+		ev.Kind, ev.Err = FileFailed, err
+	} else if ev.Size >= 0 {
+		ev.Bytes = ev.Size
+	}
+	p.mu.Unlock()
+	p.c.progress(ev)
+}
+
+func (p *fileProgress) event(kind ProgressKind) ProgressEvent {
+	return ProgressEvent{Kind: kind, Path: p.path, Size: p.size, Bytes: p.bytes, Transferred: p.transferred}
+}
+
+// progressReader reports the reads from r, the part of a file starting at
+// base, to p. Seeking back, as a retried request does, moves the position
+// back. Size makes the request carry a Content-Length.
+type progressReader struct {
+	r    io.ReadSeeker
+	p    *fileProgress
+	base int64
+	size int64
+	pos  int64
+}
+
+func (p *fileProgress) reader(r io.ReadSeeker, base, size int64) *progressReader {
+	return &progressReader{r: r, p: p, base: base, size: size}
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if n > 0 {
+		r.pos += int64(n)
+		r.p.moved(int64(n), r.base+r.pos)
+	}
+	return n, err
+}
+
+func (r *progressReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.r.Seek(offset, whence)
+	if err == nil {
+		r.pos = pos
+		r.p.moved(0, r.base+pos)
+	}
+	return pos, err
+}
+
+func (r *progressReader) Size() int64 {
+	return r.size
+}
+
+// progressWriter reports the writes to w, which continue a file at base, to p
+type progressWriter struct {
+	w   io.Writer
+	p   *fileProgress
+	pos int64
+}
+
+func (p *fileProgress) writer(w io.Writer, base int64) *progressWriter {
+	return &progressWriter{w: w, p: p, pos: base}
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	if n > 0 {
+		w.pos += int64(n)
+		w.p.moved(int64(n), w.pos)
+	}
+	return n, err
+}
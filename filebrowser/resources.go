@@ -0,0 +1,481 @@
+package filebrowser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RemoteItem is a single entry of a remote directory listing.
+type RemoteItem struct {
+	Name     string `json:"name"`
+	IsDir    bool   `json:"isDir"`
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"`
+	// Path is the full path of the entry.
+	Path      string      `json:"path"`
+	Extension string      `json:"extension"`
+	Mode      os.FileMode `json:"mode"`
+	IsSymlink bool        `json:"isSymlink"`
+	// Type is File Browser's guess at the kind of content: "text",
+	// "image", "video", "audio", "pdf" or "blob".
+	Type string `json:"type"`
+	// Checksums holds the hex digests asked of Stat, by algorithm.
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+// ChecksumAlgorithms are the algorithms the server can compute checksums with.
+var ChecksumAlgorithms = []string{"md5", "sha1", "sha256", "sha512"}
+
+// MIMEType guesses the media type of the entry from its extension.
+func (i RemoteItem) MIMEType() string {
+	if i.IsDir {
+		return "inode/directory"
+	}
+	ext := i.Extension
+	if ext == "" {
+		ext = path.Ext(i.Name)
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	if i.Type == "text" {
+		return "text/plain"
+	}
+	return "application/octet-stream"
+}
+
+// List returns the entries of the remote directory remotePath.
+func (c *Client) List(ctx context.Context, remotePath string) ([]RemoteItem, error) {
+	resp, err := c.apiRequest(ctx, "GET", "/api/resources"+encodePathPreserveSlash(remotePath), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(resp)
+	}
+	var data struct {
+		Items []RemoteItem `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		// It might be a single file response, which is not a list.
+		// The caller should have checked with IsDir.
+		// This indicates an issue if called on a file.
+		return nil, fmt.Errorf("failed to decode directory listing for '%s': %w", remotePath, err)
+	}
+	return data.Items, nil
+}
+
+// IsDir reports whether remotePath is a directory. A missing path yields an
+// error matching ErrNotFound.
+func (c *Client) IsDir(ctx context.Context, remotePath string) (bool, error) {
+	path := encodePathPreserveSlash(remotePath)
+	resp, err := c.apiRequest(ctx, "GET", "/api/resources"+path, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != 200 {
+		// The filebrowser API returns 404 for not found
+		if resp.StatusCode == http.StatusNotFound {
+			return false, fmt.Errorf("remote path '%s' not found (404): %w", remotePath, ErrNotFound)
+		}
+		return false, newAPIError(resp)
+	}
+
+	// Read body into a buffer so we can try decoding it multiple times
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// First, try to decode as a single resource object
+	var singleResource struct {
+		IsDir bool `json:"isDir"`
+	}
+	if err := json.Unmarshal(bodyBytes, &singleResource); err == nil {
+		return singleResource.IsDir, nil
+	}
+
+	// If that fails, try to decode as a directory listing (an object with an "items" array)
+	var dirListing struct {
+		Items []interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(bodyBytes, &dirListing); err == nil {
+		// If it has an "items" key, we can safely assume it's a directory.
+		return true, nil
+	}
+
+	return false, fmt.Errorf("could not determine if '%s' is a directory: unexpected JSON structure", remotePath)
+}
+
+// Stat returns the metadata of the remote file or directory remotePath. For a
+// file, the server also computes its checksum with each of algorithms. A
+// missing path yields an error matching ErrNotFound.
+func (c *Client) Stat(ctx context.Context, remotePath string, algorithms ...string) (*RemoteItem, error) {
+	var item RemoteItem
+	if err := c.getResource(ctx, remotePath, "", &item); err != nil {
+		return nil, err
+	}
+	if item.Path == "" {
+		item.Path = remotePath
+	}
+	if item.IsDir {
+		return &item, nil
+	}
+	for _, algo := range algorithms {
+		sum, err := c.Checksum(ctx, remotePath, algo)
+		if err != nil {
+			return nil, err
+		}
+		if item.Checksums == nil {
+			item.Checksums = make(map[string]string)
+		}
+		item.Checksums[algo] = sum
+	}
+	return &item, nil
+}
+
+// Checksum has the server compute the checksum of the remote file remotePath
+// with algo, one of ChecksumAlgorithms, and returns it as a hex digest.
+func (c *Client) Checksum(ctx context.Context, remotePath, algo string) (string, error) {
+	if !slices.Contains(ChecksumAlgorithms, algo) {
+		return "", fmt.Errorf("unsupported checksum algorithm %q (want %s)", algo, strings.Join(ChecksumAlgorithms, ", "))
+	}
+	var data struct {
+		Checksums map[string]string `json:"checksums"`
+	}
+	if err := c.getResource(ctx, remotePath, algo, &data); err != nil {
+		return "", err
+	}
+	sum := data.Checksums[algo]
+	if sum == "" {
+		return "", fmt.Errorf("server returned no %s checksum for '%s'", algo, remotePath)
+	}
+	return sum, nil
+}
+
+// getResource decodes the /api/resources entry of remotePath into v, asking
+// for the checksum computed with algo unless it is empty
+func (c *Client) getResource(ctx context.Context, remotePath, algo string, v any) error {
+	apiURL := "/api/resources" + encodePathPreserveSlash(remotePath)
+	if algo != "" {
+		apiURL += "?checksum=" + algo
+	}
+	resp, err := c.apiRequest(ctx, "GET", apiURL, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("remote path '%s' not found (404): %w", remotePath, ErrNotFound)
+	}
+	if resp.StatusCode != 200 {
+		return newAPIError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode resource '%s': %w", remotePath, err)
+	}
+	return nil
+}
+
+// DiskUsage is the space of the disk holding a remote path, as the server
+// reports it.
+type DiskUsage struct {
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+}
+
+// Usage returns the total and used space of the disk holding remotePath on
+// the server.
+func (c *Client) Usage(ctx context.Context, remotePath string) (*DiskUsage, error) {
+	resp, err := c.apiRequest(ctx, "GET", "/api/usage"+encodePathPreserveSlash(path.Join("/", remotePath)), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to get disk usage: %w", newAPIError(resp))
+	}
+	var usage DiskUsage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		return nil, fmt.Errorf("failed to decode disk usage: %w", err)
+	}
+	return &usage, nil
+}
+
+// Mkdir creates the remote directory remotePath, including any missing
+// parents. Creating a directory that already exists is not an error.
+func (c *Client) Mkdir(ctx context.Context, remotePath string) error {
+	cleanPath := strings.TrimSpace(remotePath)
+	if cleanPath == "/" || cleanPath == "" {
+		return fmt.Errorf("cannot create root directory")
+	}
+	trimmed := strings.Trim(cleanPath, "/")
+	if trimmed == "" {
+		return fmt.Errorf("invalid directory name")
+	}
+	if c.Config.DryRun {
+		if isDir, err := c.IsDir(ctx, remotePath); err != nil || !isDir {
+			c.planf(ctx, "Would create remote directory %s\n", remotePath)
+			c.changed(Change{Action: "mkdir", Path: remotePath})
+		}
+		return nil
+	}
+	// Use POST, no trailing slash, set browser-like headers
+	encoded := encodeSegments(remotePath)
+	url := "/api/resources" + encoded + "/?override=false"
+	headers := map[string]string{
+		"Content-Type":    "text/plain; charset=UTF-8",
+		"Content-Length":  "0",
+		"Accept-Language": "en-US,en;q=0.9",
+		"Origin":          c.Config.URL,
+		"Referer":         c.Config.URL + "/files/",
+	}
+	resp, err := c.apiRequest(ctx, "POST", url, nil, headers)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("directory creation failed for '%s': %w", remotePath, newAPIError(resp))
+	}
+	c.logf(ctx, "Directory created: %s\n", remotePath)
+	c.changed(Change{Action: "mkdir", Path: remotePath})
+	return nil
+}
+
+// Delete removes the remote file or directory remotePath. Directories are
+// removed together with their contents.
+func (c *Client) Delete(ctx context.Context, remotePath string) error {
+	encoded := encodePathPreserveSlash(remotePath)
+	if encoded == "" {
+		return fmt.Errorf("invalid path")
+	}
+	if c.Config.DryRun {
+		return c.planDelete(ctx, remotePath)
+	}
+	return c.deleteRemote(ctx, remotePath, "")
+}
+
+// deleteRemote deletes remotePath and reports the change with reason
+func (c *Client) deleteRemote(ctx context.Context, remotePath, reason string) error {
+	resp, err := c.apiRequest(ctx, "DELETE", "/api/resources"+encodePathPreserveSlash(remotePath), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != 200 && resp.StatusCode != 204 {
+		return fmt.Errorf("delete failed: %w", newAPIError(resp))
+	}
+	c.logf(ctx, "Deletion complete.\n")
+	c.changed(Change{Action: "delete", Path: remotePath, Reason: reason})
+	return nil
+}
+
+// DeleteIgnore is like Delete, but when remotePath is a directory it deletes
+// the directory contents one by one, keeping every entry matching ignore.
+// A nil ignore behaves exactly like Delete.
+func (c *Client) DeleteIgnore(ctx context.Context, remotePath string, ignore *regexp.Regexp) error {
+	if ignore == nil {
+		return c.Delete(ctx, remotePath)
+	}
+	isDir, err := c.IsDir(ctx, remotePath)
+	if err != nil {
+		return fmt.Errorf("error checking remote path: %w", err)
+	}
+
+	if !isDir {
+		// It's a file
+		if ignored(path.Base(remotePath), ignore) {
+			c.logf(ctx, "Ignoring file: %s\n", remotePath)
+			return nil
+		}
+		return c.Delete(ctx, remotePath)
+	}
+
+	// It's a directory, delete its contents recursively, honoring ignore
+	c.logf(ctx, "Deleting contents of '%s' (ignoring '%s')\n", remotePath, ignore)
+	_, err = c.deleteRecursive(ctx, remotePath, ignore)
+	return err
+}
+
+// deleteRecursive is a helper to delete directory contents, honoring an
+// ignore regex. It reports whether any entry was kept.
+func (c *Client) deleteRecursive(ctx context.Context, remoteDirPath string, ignore *regexp.Regexp) (kept bool, err error) {
+	items, err := c.List(ctx, remoteDirPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to list remote directory %s: %w", remoteDirPath, err)
+	}
+
+	for _, item := range items {
+		itemPath := path.Join(remoteDirPath, item.Name)
+		if ignored(item.Name, ignore) {
+			c.logf(ctx, "Ignoring: %s\n", itemPath)
+			kept = true
+			continue
+		}
+
+		if item.IsDir {
+			// Recursively delete contents of subdirectory first
+			subKept, err := c.deleteRecursive(ctx, itemPath, ignore)
+			if err != nil {
+				return kept, err
+			}
+			// A directory still holding ignored entries stays
+			if subKept {
+				kept = true
+				continue
+			}
+		}
+
+		// Delete the file or the now-empty directory
+		if c.Config.DryRun {
+			c.planf(ctx, "Would delete %s\n", dirPath(itemPath, item.IsDir))
+			c.changed(Change{Action: "delete", Path: itemPath})
+		} else if err := c.Delete(ctx, itemPath); err != nil {
+			return kept, err
+		}
+	}
+	return kept, nil
+}
+
+// planDelete reports what deleting remotePath would remove: its contents,
+// deepest first, and then itself
+func (c *Client) planDelete(ctx context.Context, remotePath string) error {
+	isDir, err := c.IsDir(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	if isDir {
+		if _, err := c.deleteRecursive(ctx, remotePath, nil); err != nil {
+			return err
+		}
+	}
+	c.planf(ctx, "Would delete %s\n", dirPath(remotePath, isDir))
+	c.changed(Change{Action: "delete", Path: remotePath})
+	return nil
+}
+
+// dirPath marks directories in messages with a trailing slash
+func dirPath(remotePath string, isDir bool) string {
+	if isDir && !strings.HasSuffix(remotePath, "/") {
+		return remotePath + "/"
+	}
+	return remotePath
+}
+
+// Conflict says what Copy and Move do when their destination exists.
+type Conflict int
+
+const (
+	// ConflictFail fails the operation, leaving the destination alone.
+	ConflictFail Conflict = iota
+	// ConflictOverwrite replaces the destination (the server's override).
+	ConflictOverwrite
+	// ConflictRename has the server pick a free name like "name(1).ext"
+	// instead (the server's rename).
+	ConflictRename
+	// ConflictSkip leaves the destination alone and reports success.
+	ConflictSkip
+)
+
+// Rename moves oldPath to newPath on the server. It fails if newPath exists.
+func (c *Client) Rename(ctx context.Context, oldPath, newPath string) error {
+	if err := c.patchResource(ctx, "rename", oldPath, newPath, ConflictFail); err != nil {
+		return fmt.Errorf("rename failed: %w", err)
+	}
+	c.logf(ctx, "Rename complete.\n")
+	c.changed(Change{Action: "rename", Path: newPath, Source: oldPath})
+	return nil
+}
+
+// Move moves the remote file or directory src to dst on the server.
+// onConflict says what happens when dst exists.
+func (c *Client) Move(ctx context.Context, src, dst string, onConflict Conflict) error {
+	return c.relocate(ctx, "rename", src, dst, onConflict)
+}
+
+// Copy copies the remote file or directory src to dst on the server, without
+// transferring its contents. Directories are copied with everything in them.
+// onConflict says what happens when dst exists.
+func (c *Client) Copy(ctx context.Context, src, dst string, onConflict Conflict) error {
+	return c.relocate(ctx, "copy", src, dst, onConflict)
+}
+
+// relocate has the server rename (move) or copy src to dst
+func (c *Client) relocate(ctx context.Context, action, src, dst string, onConflict Conflict) error {
+	verb, done := "move", "Moved"
+	if action == "copy" {
+		verb, done = "copy", "Copied"
+	}
+	src, dst = path.Join("/", src), path.Join("/", dst)
+	if src == dst || strings.HasPrefix(dst, strings.TrimSuffix(src, "/")+"/") {
+		return fmt.Errorf("cannot %s '%s' into itself", verb, src)
+	}
+	if onConflict == ConflictSkip {
+		if _, err := c.IsDir(ctx, dst); err == nil {
+			c.logf(ctx, "Skipping %s: %s exists\n", src, dst)
+			return nil
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	if err := c.patchResource(ctx, action, src, dst, onConflict); err != nil {
+		return fmt.Errorf("%s of %s failed: %w", verb, src, err)
+	}
+	c.logf(ctx, "%s %s to %s\n", done, src, dst)
+	c.changed(Change{Action: action, Path: dst, Source: src})
+	return nil
+}
+
+// patchResource has the server rename or copy src to dst
+func (c *Client) patchResource(ctx context.Context, action, src, dst string, onConflict Conflict) error {
+	query := url.Values{}
+	query.Set("action", action)
+	// The server unescapes the destination once more after reading the query
+	query.Set("destination", url.QueryEscape(dst))
+	query.Set("override", strconv.FormatBool(onConflict == ConflictOverwrite))
+	query.Set("rename", strconv.FormatBool(onConflict == ConflictRename))
+	apiURL := "/api/resources" + encodePathPreserveSlash(src) + "?" + query.Encode()
+	resp, err := c.apiRequest(ctx, "PATCH", apiURL, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("'%s' %w", dst, ErrExists)
+	}
+	if resp.StatusCode != 200 {
+		return newAPIError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package filebrowser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// retryBaseDelay is the wait before the first retry; it doubles for
+	// every further attempt up to retryMaxDelay.
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+	// maxRetryAfter caps how long a Retry-After header can make us wait.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// RetriedRequest describes a request that needed more than one attempt.
+type RetriedRequest struct {
+	Method   string
+	Path     string
+	Attempts int
+	// Err is the transient failure of the last attempt, or nil if the
+	// request eventually went through.
+	Err error
+}
+
+// Retried returns the requests that were retried so far, in order.
+func (c *Client) Retried() []RetriedRequest {
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+	return append([]RetriedRequest(nil), c.retried...)
+}
+
+func (c *Client) recordRetry(method, path string, attempts int, err error) {
+	path, _, _ = strings.Cut(path, "?")
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+	c.retried = append(c.retried, RetriedRequest{Method: method, Path: path, Attempts: attempts, Err: err})
+}
+
+// transientFailure returns why an attempt failed in a way worth retrying,
+// or nil if it succeeded or failed for good. State-changing requests other
+// than POST (which this API only uses for uploads, directory creation and
+// login, all safe to repeat) are only retried when the server certainly did
+// not act on them.
+func transientFailure(method string, resp *http.Response, err error) error {
+	idempotent := method != http.MethodPatch
+	if err != nil {
+		var unreachable *UnreachableError
+		if !errors.As(err, &unreachable) {
+			return nil
+		}
+		var opErr *net.OpError
+		if idempotent || (errors.As(err, &opErr) && opErr.Op == "dial") {
+			return err
+		}
+		return nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return errors.New(resp.Status)
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		if idempotent {
+			return errors.New(resp.Status)
+		}
+	}
+	return nil
+}
+
+// attemptError is a transient failure of a request sent only once;
+// wait is the delay the server asked for in Retry-After, if any
+type attemptError struct {
+	err  error
+	wait time.Duration
+}
+
+func (e *attemptError) Error() string {
+	return e.err.Error()
+}
+
+func (e *attemptError) Unwrap() error {
+	return e.err
+}
+
+// resumeDelay returns how long to wait before resuming a transfer whose
+// last attempt failed with err, the given number of attempts in a row
+func resumeDelay(err error, failures int) time.Duration {
+	var aerr *attemptError
+	if errors.As(err, &aerr) && aerr.wait > 0 {
+		return aerr.wait
+	}
+	return retryDelay(nil, failures)
+}
+
+// retryDelay returns how long to wait before the given retry (1 for the
+// first): what the server asked for in Retry-After, or an exponential
+// backoff with jitter so parallel clients do not retry in lockstep.
+func retryDelay(resp *http.Response, retry int) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(d, maxRetryAfter)
+		}
+	}
+	d := retryBaseDelay << min(retry-1, 16)
+	if d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter parses a Retry-After value in seconds or as an HTTP date
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// formatDelay renders a retry delay for messages, e.g. "1.3s"
+func formatDelay(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
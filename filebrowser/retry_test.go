@@ -0,0 +1,45 @@
+package filebrowser
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 5 ", 5 * time.Second, true},
+		{"0", 0, true},
+		{"Sun, 02 Mar 2025 10:00:30 GMT", 30 * time.Second, true},
+		{"Sun, 02 Mar 2025 09:59:00 GMT", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResumeDelay(t *testing.T) {
+	asked := fmt.Errorf("chunk failed: %w", &attemptError{err: errors.New("503 Service Unavailable"), wait: 7 * time.Second})
+	if got := resumeDelay(asked, 1); got != 7*time.Second {
+		t.Errorf("resumeDelay with Retry-After = %v, want 7s", got)
+	}
+	// Without Retry-After the backoff of the third retry is 1-2s
+	for _, err := range []error{&attemptError{err: errors.New("502 Bad Gateway")}, errors.New("unexpected EOF")} {
+		if got := resumeDelay(err, 3); got < time.Second || got > 2*time.Second {
+			t.Errorf("resumeDelay(%v, 3) = %v, want between 1s and 2s", err, got)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package filebrowser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// SearchTypes are the kinds of files a search can be narrowed to.
+var SearchTypes = []string{"image", "audio", "video", "pdf"}
+
+// SearchOptions narrow a search.
+type SearchOptions struct {
+	// Type is one of SearchTypes, or empty for entries of any kind.
+	Type string
+	// CaseSensitive makes the query match names in the same case only.
+	CaseSensitive bool
+}
+
+// SearchResult is an entry found by Search.
+type SearchResult struct {
+	// Path is the full path of the entry.
+	Path  string `json:"path"`
+	IsDir bool   `json:"dir"`
+}
+
+// Search has the server look for the entries below remotePath whose names
+// match query and calls fn for each of them as soon as it arrives, without
+// waiting for the server to finish. An error from fn stops the search.
+func (c *Client) Search(ctx context.Context, remotePath, query string, opts SearchOptions, fn func(SearchResult) error) error {
+	dir := path.Join("/", remotePath)
+	// The server reads its filters from the query itself
+	terms := []string{}
+	if opts.Type != "" {
+		terms = append(terms, "type:"+opts.Type)
+	}
+	if opts.CaseSensitive {
+		terms = append(terms, "case:sensitive")
+	}
+	terms = append(terms, query)
+	apiURL := "/api/search" + encodePathPreserveSlash(dir) + "?query=" + url.QueryEscape(strings.Join(terms, " "))
+	resp, err := c.apiRequest(ctx, "GET", apiURL, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("remote path '%s' not found (404): %w", dir, ErrNotFound)
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("search failed: %w", newAPIError(resp))
+	}
+
+	// Decode the array one result at a time
+	dec := json.NewDecoder(resp.Body)
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to decode search results: %w", err)
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("failed to decode search results: unexpected %v", tok)
+	}
+	for dec.More() {
+		var res SearchResult
+		if err := dec.Decode(&res); err != nil {
+			return fmt.Errorf("failed to decode search results: %w", err)
+		}
+		// Paths are relative to the directory searched
+		res.Path = path.Join(dir, res.Path)
+		if err := fn(res); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("failed to decode search results: %w", err)
+	}
+	return nil
+}
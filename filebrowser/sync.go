@@ -0,0 +1,520 @@
+package filebrowser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// hashSizeLimit is the largest file size for which sync compares checksums;
+// bigger files are considered in sync when their sizes match.
+const hashSizeLimit = 1024 * 1024 // 1MB
+
+// SyncTo makes remotePath mirror the local file or directory localPath:
+// missing or changed files are uploaded and remote entries that do not exist
+// locally are deleted. Entries matching ignore (may be nil) are left alone on
+// both sides. Up to Config.Jobs files are transferred at a time; failures of
+// individual files are reported to c.Err and returned together as a
+// *BulkError.
+func (c *Client) SyncTo(ctx context.Context, localPath, remotePath string, ignore *regexp.Regexp) error {
+	if ignore != nil {
+		c.logf(ctx, "Syncing from local '%s' to remote '%s' (ignoring '%s')\n", localPath, remotePath, ignore)
+	} else {
+		c.logf(ctx, "Syncing from local '%s' to remote '%s'\n", localPath, remotePath)
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("error accessing local path: %w", err)
+	}
+	if !info.IsDir() {
+		if ignored(info.Name(), ignore) {
+			c.logf(ctx, "Ignoring file: %s\n", info.Name())
+			return nil
+		}
+		remoteFilePath := path.Join(remotePath, info.Name())
+		c.plan(1, info.Size())
+		return c.syncFileToRemote(ctx, localPath, remoteFilePath, info, newRemoteDirs(c))
+	}
+
+	// Collect all local relative paths
+	localPaths := make(map[string]os.FileInfo)
+	walkErr := filepath.Walk(localPath, func(currentLocalPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(localPath, currentLocalPath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == "." {
+			localPaths[relPath] = info
+			return nil
+		}
+		if ignoredPath(relPath, ignore) {
+			// If this is a directory, skip the whole subtree
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		localPaths[relPath] = info
+		return nil
+	})
+	if walkErr != nil {
+		return fmt.Errorf("error walking local path: %w", walkErr)
+	}
+
+	files, total := 0, int64(0)
+	for _, info := range localPaths {
+		if !info.IsDir() {
+			files, total = files+1, total+info.Size()
+		}
+	}
+	c.plan(files, total)
+
+	// Sync local to remote (create/update); parents sort before their
+	// children, so directories exist before the files in them are queued
+	r := c.newTaskRunner(ctx)
+	remoteDirs := newRemoteDirs(c)
+	for _, relPath := range sortedKeys(localPaths) {
+		if ctx.Err() != nil {
+			break
+		}
+		if relPath == "." {
+			continue
+		}
+		info := localPaths[relPath]
+		remoteItemPath := path.Join(remotePath, relPath)
+		if info.IsDir() {
+			_ = r.do(func(ctx context.Context) error {
+				return c.Mkdir(ctx, remoteItemPath)
+			})
+		} else {
+			r.run(func(ctx context.Context) error {
+				return c.syncFileToRemote(ctx, filepath.Join(localPath, relPath), remoteItemPath, info, remoteDirs)
+			})
+		}
+	}
+
+	// Delete remote files/dirs not in local
+	var walkRemote func(string, string)
+	walkRemote = func(remoteDir, localDir string) {
+		var remoteItems []RemoteItem
+		err := r.do(func(ctx context.Context) error {
+			var err error
+			remoteItems, err = c.List(ctx, remoteDir)
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				// Not created yet, as in a dry run: nothing to delete
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to list remote directory %s: %w", remoteDir, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+		for _, item := range remoteItems {
+			if ctx.Err() != nil {
+				return
+			}
+			if ignored(item.Name, ignore) {
+				continue
+			}
+			rel := path.Join(strings.TrimPrefix(remoteDir, remotePath), item.Name)
+			rel = strings.TrimPrefix(rel, "/")
+			localItem, exists := localPaths[rel]
+			remoteItemPath := path.Join(remoteDir, item.Name)
+			if !exists {
+				r.run(func(ctx context.Context) error {
+					if c.Config.DryRun {
+						c.planf(ctx, "Would delete remote %s (not in source)\n", dirPath(remoteItemPath, item.IsDir))
+						c.changed(Change{Action: "delete", Path: remoteItemPath, Reason: "not in source"})
+						return nil
+					}
+					if item.IsDir {
+						c.logf(ctx, "Deleting remote directory not in source: %s\n", remoteItemPath)
+					} else {
+						c.logf(ctx, "Deleting remote file not in source: %s\n", remoteItemPath)
+					}
+					return c.deleteRemote(ctx, remoteItemPath, "not in source")
+				})
+			} else if item.IsDir && localItem.IsDir() {
+				walkRemote(remoteItemPath, filepath.Join(localDir, item.Name))
+			}
+		}
+	}
+	if ctx.Err() == nil {
+		walkRemote(remotePath, localPath)
+	}
+	return r.wait()
+}
+
+func (c *Client) syncFileToRemote(ctx context.Context, localPath, remotePath string, localFileInfo os.FileInfo, remoteDirs *remoteDirs) error {
+	upload := func(reason string) error {
+		if err := c.uploadFile(ctx, localPath, path.Dir(remotePath), reason); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", localPath, err)
+		}
+		return nil
+	}
+
+	remoteItem, err := remoteDirs.lookup(ctx, remotePath)
+	if err != nil {
+		// Assume directory doesn't exist, so upload
+		return upload("new file")
+	}
+
+	if remoteItem == nil {
+		// File does not exist on remote, upload
+		return upload("new file")
+	}
+
+	// File exists on remote, compare
+	if localFileInfo.Size() != remoteItem.Size {
+		c.logf(ctx, "File size mismatch for %s. Uploading.\n", localPath)
+		return upload("size differs")
+	}
+	if localFileInfo.Size() >= hashSizeLimit {
+		c.logf(ctx, "File %s is already in sync (size match, not hashing >1MB).\n", localPath)
+		c.skipFile(remotePath, localFileInfo.Size())
+		return nil
+	}
+	localHash, err := getLocalFileHash(localPath)
+	if err != nil {
+		err = fmt.Errorf("error hashing local file %s: %w", localPath, err)
+		c.failFile(remotePath, err)
+		return err
+	}
+	remoteHash, err := c.Checksum(ctx, remotePath, "sha256")
+	if err != nil {
+		c.warnf(ctx, "Error fetching remote hash for %s: %v\n", remotePath, err)
+		// fallback: assume not in sync
+		remoteHash = ""
+	}
+	if !sameHash(localHash, remoteHash) {
+		c.logf(ctx, "File hash mismatch for %s. Uploading.\n", localPath)
+		return upload("checksum differs")
+	}
+	c.logf(ctx, "File %s is already in sync.\n", localPath)
+	c.skipFile(remotePath, localFileInfo.Size())
+	return nil
+}
+
+// remoteDirs caches the listings of remote directories during a sync, so the
+// files of a directory are compared against a single listing
+type remoteDirs struct {
+	c    *Client
+	mu   sync.Mutex
+	dirs map[string]*remoteDir
+}
+
+type remoteDir struct {
+	once  sync.Once
+	items []RemoteItem
+	err   error
+}
+
+func newRemoteDirs(c *Client) *remoteDirs {
+	return &remoteDirs{c: c, dirs: make(map[string]*remoteDir)}
+}
+
+// lookup returns the listing entry of remotePath, or nil if its directory
+// does not contain it
+func (d *remoteDirs) lookup(ctx context.Context, remotePath string) (*RemoteItem, error) {
+	dirPath := path.Dir(remotePath)
+	d.mu.Lock()
+	dir, ok := d.dirs[dirPath]
+	if !ok {
+		dir = &remoteDir{}
+		d.dirs[dirPath] = dir
+	}
+	d.mu.Unlock()
+	dir.once.Do(func() {
+		dir.items, dir.err = d.c.List(ctx, dirPath)
+	})
+	if dir.err != nil {
+		return nil, dir.err
+	}
+	for i, item := range dir.items {
+		if item.Name == path.Base(remotePath) {
+			return &dir.items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SyncFrom makes the local path localPath mirror the remote file or
+// directory remotePath: missing or changed files are downloaded and local
+// entries that do not exist remotely are deleted. Top-level entries matching
+// ignore (may be nil) are left alone on both sides. Up to Config.Jobs files
+// are transferred at a time; failures of individual files are reported to
+// c.Err and returned together as a *BulkError.
+func (c *Client) SyncFrom(ctx context.Context, remotePath, localPath string, ignore *regexp.Regexp) error {
+	isDir, err := c.IsDir(ctx, remotePath)
+	if err != nil {
+		return fmt.Errorf("error checking remote path type: %w", err)
+	}
+	if !isDir {
+		if ignored(path.Base(remotePath), ignore) {
+			c.logf(ctx, "Ignoring file: %s\n", path.Base(remotePath))
+			return nil
+		}
+		remoteItem, err := newRemoteDirs(c).lookup(ctx, remotePath)
+		if err != nil {
+			return fmt.Errorf("failed to list remote directory %s: %w", path.Dir(remotePath), err)
+		}
+		if remoteItem == nil {
+			return fmt.Errorf("remote file %s not found", remotePath)
+		}
+		c.plan(1, remoteItem.Size)
+		return c.syncFileFromRemote(ctx, remotePath, localPath, *remoteItem)
+	}
+
+	if ignore != nil {
+		c.logf(ctx, "Syncing remote directory '%s' to local '%s' (ignoring '%s')\n", remotePath, localPath, ignore)
+	} else {
+		c.logf(ctx, "Syncing remote directory '%s' to local '%s'\n", remotePath, localPath)
+	}
+	if err := c.mkdirLocal(ctx, localPath); err != nil {
+		return err
+	}
+
+	// Collect all remote items
+	remoteItems := make(map[string]RemoteItem)
+	// An incomplete listing would delete local files that still exist
+	// remotely, so any listing failure aborts the sync.
+	var collectRemote func(string, string, bool) error
+	collectRemote = func(rPath, relBase string, isTopLevel bool) error {
+		items, err := c.List(ctx, rPath)
+		if err != nil {
+			return fmt.Errorf("failed to list remote directory %s: %w", rPath, err)
+		}
+		for _, item := range items {
+			if isTopLevel && ignored(item.Name, ignore) {
+				continue
+			}
+			rel := path.Join(relBase, item.Name)
+			remoteItems[rel] = item
+			if item.IsDir {
+				if err := collectRemote(path.Join(rPath, item.Name), rel, false); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := collectRemote(remotePath, "", true); err != nil {
+		return err
+	}
+
+	// Collect all local items
+	localItems := make(map[string]os.FileInfo)
+	walkErr := filepath.Walk(localPath, func(currentLocalPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if c.Config.DryRun && currentLocalPath == localPath && os.IsNotExist(err) {
+				// A dry run does not create the destination
+				return nil
+			}
+			return err
+		}
+		relPath, err := filepath.Rel(localPath, currentLocalPath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		// Only ignore at the top level
+		if relPath != "." && !strings.Contains(relPath, "/") && ignored(info.Name(), ignore) {
+			return nil // skip
+		}
+		localItems[relPath] = info
+		return nil
+	})
+	if walkErr != nil {
+		return fmt.Errorf("error walking local path: %w", walkErr)
+	}
+
+	files, total := 0, int64(0)
+	for _, item := range remoteItems {
+		if !item.IsDir {
+			files, total = files+1, total+item.Size
+		}
+	}
+	c.plan(files, total)
+
+	// Download or update files/dirs from remote; parents sort before their
+	// children, so directories exist before the files in them are queued
+	r := c.newTaskRunner(ctx)
+	for _, rel := range sortedKeys(remoteItems) {
+		if ctx.Err() != nil {
+			break
+		}
+		item := remoteItems[rel]
+		newRemotePath := path.Join(remotePath, rel)
+		newLocalPath := filepath.Join(localPath, rel)
+		if item.IsDir {
+			if _, exists := localItems[rel]; exists {
+				continue
+			}
+			_ = r.do(func(ctx context.Context) error {
+				if err := c.mkdirLocal(ctx, newLocalPath); err != nil {
+					return err
+				}
+				c.logf(ctx, "Directory created: %s\n", newLocalPath)
+				return nil
+			})
+		} else {
+			r.run(func(ctx context.Context) error {
+				return c.syncFileFromRemote(ctx, newRemotePath, newLocalPath, item)
+			})
+		}
+	}
+	if err := r.wait(); ctx.Err() != nil {
+		return err
+	}
+
+	// Delete local files/dirs not in remote
+	var deletedDirs []string
+	for _, rel := range sortedKeys(localItems) {
+		if ctx.Err() != nil {
+			break
+		}
+		info := localItems[rel]
+		if rel == "." {
+			continue
+		}
+		if _, exists := remoteItems[rel]; exists {
+			continue
+		}
+		// Keep interrupted downloads of remote files for the next run to resume
+		if _, exists := remoteItems[strings.TrimSuffix(rel, partialSuffix)]; exists && !info.IsDir() {
+			continue
+		}
+		// Only ignore at the top level
+		if !strings.Contains(rel, "/") && ignored(info.Name(), ignore) {
+			continue
+		}
+		// Already gone with a deleted directory
+		if slices.ContainsFunc(deletedDirs, func(dir string) bool { return strings.HasPrefix(rel, dir+"/") }) {
+			continue
+		}
+		localPathToDelete := filepath.Join(localPath, rel)
+		if info.IsDir() {
+			deletedDirs = append(deletedDirs, rel)
+		}
+		_ = r.do(func(ctx context.Context) error {
+			if c.Config.DryRun {
+				c.planf(ctx, "Would delete local %s (not in remote)\n", dirPath(localPathToDelete, info.IsDir()))
+				c.changed(Change{Action: "delete", Path: localPathToDelete, Local: true, Reason: "not in remote"})
+				return nil
+			}
+			if info.IsDir() {
+				c.logf(ctx, "Deleting local directory not in remote: %s\n", localPathToDelete)
+				if err := os.RemoveAll(localPathToDelete); err != nil {
+					return fmt.Errorf("error deleting directory: %w", err)
+				}
+			} else {
+				c.logf(ctx, "Deleting local file not in remote: %s\n", localPathToDelete)
+				if err := os.Remove(localPathToDelete); err != nil {
+					return fmt.Errorf("error deleting file: %w", err)
+				}
+			}
+			c.changed(Change{Action: "delete", Path: localPathToDelete, Local: true, Reason: "not in remote"})
+			return nil
+		})
+	}
+	return r.wait()
+}
+
+func (c *Client) syncFileFromRemote(ctx context.Context, remotePath, localPath string, remoteItem RemoteItem) error {
+	download := func(reason string) error {
+		if err := c.downloadFile(ctx, remotePath, localPath, reason); err != nil {
+			return fmt.Errorf("failed to download %s: %w", remotePath, err)
+		}
+		return nil
+	}
+
+	localFileInfo, err := os.Stat(localPath)
+	if err != nil {
+		// File does not exist locally, download
+		return download("missing locally")
+	}
+
+	// File exists locally, compare
+	if localFileInfo.Size() != remoteItem.Size {
+		c.logf(ctx, "File size mismatch for %s. Downloading.\n", remotePath)
+		return download("size differs")
+	}
+	if localFileInfo.Size() >= hashSizeLimit {
+		c.logf(ctx, "File %s is already in sync (size match, not hashing >1MB).\n", localPath)
+		c.skipFile(remotePath, localFileInfo.Size())
+		return nil
+	}
+	localHash, err := getLocalFileHash(localPath)
+	if err != nil {
+		err = fmt.Errorf("error hashing local file %s: %w", localPath, err)
+		c.failFile(remotePath, err)
+		return err
+	}
+	remoteHash, err := c.Checksum(ctx, remotePath, "sha256")
+	if err != nil {
+		c.warnf(ctx, "Error fetching remote hash for %s: %v\n", remotePath, err)
+		// fallback: assume not in sync
+		remoteHash = ""
+	}
+	if !sameHash(localHash, remoteHash) {
+		c.logf(ctx, "File hash mismatch for %s. Downloading.\n", remotePath)
+		return download("checksum differs")
+	}
+	c.logf(ctx, "File %s is already in sync.\n", localPath)
+	c.skipFile(remotePath, localFileInfo.Size())
+	return nil
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// sameHash compares two hex digests, ignoring case and surrounding space.
+// An empty digest never matches.
+func sameHash(a, b string) bool {
+	a = strings.ToLower(strings.TrimSpace(a))
+	b = strings.ToLower(strings.TrimSpace(b))
+	return a != "" && a == b
+}
+
+func getLocalFileHash(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,813 @@
+package filebrowser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// partialSuffix is appended to the name of a file while it is downloaded;
+// the file only gets its real name once it is complete.
+const partialSuffix = ".part"
+
+// errStalePartial means the partial file of a download has to be discarded
+var errStalePartial = errors.New("partial file does not match the remote file")
+
+// Upload copies the local file or directory localPath into remoteDir.
+// Directories are uploaded recursively as remoteDir/<basename>, on up to
+// Config.Jobs files at a time; files and directories matching ignore (may be
+// nil) are skipped. Failures of individual files do not stop the upload, they
+// are reported to c.Err and returned together as a *BulkError.
+func (c *Client) Upload(ctx context.Context, localPath, remoteDir string, ignore *regexp.Regexp) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("error accessing local path: %w", err)
+	}
+	if !info.IsDir() {
+		if ignored(info.Name(), ignore) {
+			c.logf(ctx, "Ignoring file: %s\n", info.Name())
+			return nil
+		}
+		c.plan(1, info.Size())
+		reason := c.uploadReason(ctx, newRemoteDirs(c), path.Join("/", remoteDir, info.Name()))
+		if err := c.uploadFile(ctx, localPath, remoteDir, reason); err != nil {
+			return fmt.Errorf("upload failed: %w", err)
+		}
+		c.logf(ctx, "Upload complete.\n")
+		return nil
+	}
+	if ignore != nil {
+		c.logf(ctx, "Uploading directory '%s' to '%s' (ignoring '%s')\n", localPath, remoteDir, ignore)
+	} else {
+		c.logf(ctx, "Uploading directory '%s' to '%s'\n", localPath, remoteDir)
+	}
+	localDirName := filepath.Base(localPath)
+	if ignored(localDirName, ignore) {
+		c.logf(ctx, "Ignoring directory: %s\n", localDirName)
+		return nil
+	}
+	fullRemoteDir := path.Join(remoteDir, localDirName)
+	if err := c.Mkdir(ctx, fullRemoteDir); err != nil {
+		return err
+	}
+	// Directories are created while walking, the files in them are queued
+	// for the workers once the total size is known
+	type upload struct{ localPath, remoteDir string }
+	var uploads []upload
+	var total int64
+	r := c.newTaskRunner(ctx)
+	walkErr := filepath.Walk(localPath, func(currentLocalPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(localPath, currentLocalPath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == "." {
+			return nil
+		}
+		if ignoredPath(relPath, ignore) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		remoteItemPath := path.Join(fullRemoteDir, relPath)
+		if info.IsDir() {
+			err := r.do(func(ctx context.Context) error {
+				c.logf(ctx, "Creating remote directory: %s\n", remoteItemPath)
+				return c.Mkdir(ctx, remoteItemPath)
+			})
+			if err != nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		uploads = append(uploads, upload{currentLocalPath, path.Dir(remoteItemPath)})
+		total += info.Size()
+		return nil
+	})
+	if walkErr != nil {
+		return fmt.Errorf("error during directory upload: %w", walkErr)
+	}
+	c.plan(len(uploads), total)
+	remoteDirs := newRemoteDirs(c)
+	for _, u := range uploads {
+		r.run(func(ctx context.Context) error {
+			c.logf(ctx, "Uploading file %s to %s\n", u.localPath, u.remoteDir)
+			reason := c.uploadReason(ctx, remoteDirs, path.Join("/", u.remoteDir, filepath.Base(u.localPath)))
+			if err := c.uploadFile(ctx, u.localPath, u.remoteDir, reason); err != nil {
+				return fmt.Errorf("failed to upload %s: %w", u.localPath, err)
+			}
+			return nil
+		})
+	}
+	if err := r.wait(); err != nil {
+		return err
+	}
+	c.logf(ctx, "Directory upload complete.\n")
+	return nil
+}
+
+// uploadReason says whether an upload to remoteFile adds or replaces a file,
+// for the plan of a dry run
+func (c *Client) uploadReason(ctx context.Context, remoteDirs *remoteDirs, remoteFile string) string {
+	if !c.Config.DryRun {
+		return ""
+	}
+	if item, err := remoteDirs.lookup(ctx, remoteFile); err == nil && item != nil {
+		return "replaces the remote file"
+	}
+	return "new file"
+}
+
+// uploadFile uploads localPath into remoteDir; reason says why, for the plan
+// of a dry run
+func (c *Client) uploadFile(ctx context.Context, localPath, remoteDir, reason string) (err error) {
+	remoteFile := path.Join("/", remoteDir, filepath.Base(localPath))
+	if c.Config.DryRun {
+		c.planf(ctx, "Would upload %s to %s (%s)\n", localPath, remoteFile, reason)
+		c.changed(Change{Action: "upload", Path: remoteFile, Source: localPath, Reason: reason})
+		return nil
+	}
+	progress := c.startFile(remoteFile, -1)
+	defer func() {
+		progress.done(err)
+	}()
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	progress.setSize(info.Size())
+	if err := c.sendFile(ctx, file, info.Size(), remoteFile, progress); err != nil || !c.Config.Verify {
+		return err
+	}
+	return c.verifyUpload(ctx, io.NewSectionReader(file, 0, info.Size()), remoteFile)
+}
+
+// sendFile uploads file, which is size bytes long, to remoteFile: in chunks
+// from Config.ChunkThreshold on, in a single request otherwise
+func (c *Client) sendFile(ctx context.Context, file *os.File, size int64, remoteFile string, progress *fileProgress) error {
+	if c.Config.ChunkThreshold > 0 && size >= c.Config.ChunkThreshold {
+		err := c.uploadChunked(ctx, file, size, remoteFile, progress)
+		if !errors.Is(err, errTusUnsupported) {
+			return err
+		}
+		c.warnf(ctx, "Warning: %v, uploading %s in one request\n", err, file.Name())
+	}
+	return c.postFile(ctx, remoteFile, progress.reader(file, 0, size))
+}
+
+// UploadReader uploads what r yields to the remote file remoteFile, e.g. the
+// output of another program, creating its directory if needed. r is read
+// only once, in chunks of Config.ChunkSize: input that fits into one chunk is
+// sent in a single request, longer input in chunks through the resumable
+// upload endpoint, so a failed chunk can be sent again. Servers that need the
+// length of such uploads up front get the input in one request that cannot
+// be retried. A regular *os.File is uploaded like Upload does.
+func (c *Client) UploadReader(ctx context.Context, r io.Reader, remoteFile string) (err error) {
+	remoteFile = path.Join("/", remoteFile)
+	if c.Config.DryRun {
+		reason := c.uploadReason(ctx, newRemoteDirs(c), remoteFile)
+		c.planf(ctx, "Would upload the input to %s (%s)\n", remoteFile, reason)
+		c.changed(Change{Action: "upload", Path: remoteFile, Source: "-", Reason: reason})
+		return nil
+	}
+	c.plan(1, -1)
+	progress := c.startFile(remoteFile, -1)
+	defer func() {
+		progress.done(err)
+	}()
+	if f, ok := r.(*os.File); ok {
+		// Redirected from a file that was not read yet
+		info, err := f.Stat()
+		if pos, _ := f.Seek(0, io.SeekCurrent); err == nil && info.Mode().IsRegular() && pos == 0 {
+			progress.setSize(info.Size())
+			if err := c.sendFile(ctx, f, info.Size(), remoteFile, progress); err != nil || !c.Config.Verify {
+				return err
+			}
+			return c.verifyUpload(ctx, io.NewSectionReader(f, 0, info.Size()), remoteFile)
+		}
+	}
+	if !c.Config.Verify {
+		return c.sendStream(ctx, r, remoteFile, progress)
+	}
+	// The input can only be read once, so it is hashed on the way
+	hash := sha256.New()
+	if err := c.sendStream(ctx, io.TeeReader(r, hash), remoteFile, progress); err != nil {
+		return err
+	}
+	return c.verifyChecksum(ctx, remoteFile, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// sendStream uploads what r yields to remoteFile for UploadReader
+func (c *Client) sendStream(ctx context.Context, r io.Reader, remoteFile string, progress *fileProgress) error {
+	chunkSize := c.Config.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	first := make([]byte, chunkSize)
+	n, err := io.ReadFull(r, first)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		progress.setSize(int64(n))
+		return c.postFile(ctx, remoteFile, progress.reader(bytes.NewReader(first[:n]), 0, int64(n)))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	if c.Config.ChunkThreshold > 0 {
+		err := c.uploadStream(ctx, first, r, remoteFile, progress)
+		if !errors.Is(err, errTusUnsupported) && !errors.Is(err, errTusNeedsLength) {
+			return err
+		}
+		c.warnf(ctx, "Warning: %v, uploading %s in one request\n", err, remoteFile)
+	}
+	// The input cannot be sent twice, so the directory has to exist first
+	if dir := path.Dir(remoteFile); dir != "/" {
+		if isDir, err := c.IsDir(ctx, dir); err != nil || !isDir {
+			if err := c.Mkdir(ctx, dir); err != nil {
+				return err
+			}
+		}
+	}
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(first), r), progress.writer(io.Discard, 0))
+	return c.postFile(ctx, remoteFile, body)
+}
+
+// verifyUpload compares the SHA-256 checksum of what local yields, the
+// contents of a file just uploaded, with the server's checksum of remoteFile
+func (c *Client) verifyUpload(ctx context.Context, local io.Reader, remoteFile string) error {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, local); err != nil {
+		return fmt.Errorf("cannot verify %s: %w", remoteFile, err)
+	}
+	return c.verifyChecksum(ctx, remoteFile, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// verifyChecksum compares localHash, the SHA-256 checksum of a file on this
+// side, with the server's checksum of remotePath
+func (c *Client) verifyChecksum(ctx context.Context, remotePath, localHash string) error {
+	remoteHash, err := c.Checksum(ctx, remotePath, "sha256")
+	if err != nil {
+		return fmt.Errorf("cannot verify %s: %w", remotePath, err)
+	}
+	if !sameHash(localHash, remoteHash) {
+		return fmt.Errorf("%w: %s is %s here, %s on the server", ErrChecksumMismatch, remotePath, localHash, remoteHash)
+	}
+	c.logf(ctx, "Checksum of %s verified\n", remotePath)
+	return nil
+}
+
+// postFile uploads body to remoteFile in a single request. If the directory
+// of remoteFile is missing it is created and a body that can be rewound is
+// sent again.
+func (c *Client) postFile(ctx context.Context, remoteFile string, body io.Reader) error {
+	url := "/api/resources" + encodePathPreserveSlash(remoteFile) + "?override=true"
+	headers := map[string]string{"Content-Type": "application/octet-stream"}
+
+	// First attempt
+	resp, err := c.apiRequest(ctx, "POST", url, body, headers)
+	if err != nil {
+		return err
+	}
+
+	// If 404, directory may not exist. Create it and retry.
+	if resp.StatusCode == 404 && rewindable(body) {
+		_ = resp.Body.Close()
+		if err := c.Mkdir(ctx, path.Dir(remoteFile)); err != nil {
+			return err
+		}
+		if err := rewind(body); err != nil {
+			return err
+		}
+		resp, err = c.apiRequest(ctx, "POST", url, body, headers)
+		if err != nil {
+			return err
+		}
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("server response %d", resp.StatusCode)
+	}
+
+	// Read and discard the response body to ensure the upload is complete
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Download copies the remote file or directory remotePath to localPath.
+// If localPath is an existing directory the item is placed inside it.
+// Directories are downloaded recursively, skipping entries matching ignore
+// (may be nil), on up to Config.Jobs files at a time; failures of individual
+// files are reported to c.Err and returned together as a *BulkError.
+func (c *Client) Download(ctx context.Context, remotePath, localPath string, ignore *regexp.Regexp) error {
+	info, err := os.Stat(localPath)
+	if err == nil && info.IsDir() {
+		baseName := filepath.Base(remotePath)
+		localPath = filepath.Join(localPath, baseName)
+	} else if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error accessing local path %s: %w", localPath, err)
+	}
+	isDir, err := c.IsDir(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	if !isDir {
+		if ignored(path.Base(remotePath), ignore) {
+			c.logf(ctx, "Ignoring file: %s\n", path.Base(remotePath))
+			return nil
+		}
+		c.plan(1, -1)
+		return c.downloadFile(ctx, remotePath, localPath, downloadReason(localPath))
+	}
+	if ignore != nil {
+		c.logf(ctx, "Downloading directory '%s' to '%s' (ignoring '%s')...\n", remotePath, localPath, ignore)
+	} else {
+		c.logf(ctx, "Downloading directory '%s' to '%s'...\n", remotePath, localPath)
+	}
+	if err := c.mkdirLocal(ctx, localPath); err != nil {
+		return err
+	}
+	// Directories are listed and created first, the files in them are
+	// queued for the workers once the total size is known
+	type download struct{ remotePath, localPath string }
+	var downloads []download
+	var total int64
+	r := c.newTaskRunner(ctx)
+	var downloadDir func(string, string)
+	downloadDir = func(rPath, lPath string) {
+		var items []RemoteItem
+		err := r.do(func(ctx context.Context) error {
+			var err error
+			if items, err = c.List(ctx, rPath); err != nil {
+				return fmt.Errorf("failed to list remote directory %s: %w", rPath, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+		for _, item := range items {
+			if ctx.Err() != nil {
+				return
+			}
+			if ignored(item.Name, ignore) {
+				continue
+			}
+			remoteItemPath := path.Join(rPath, item.Name)
+			localItemPath := filepath.Join(lPath, item.Name)
+			if item.IsDir {
+				err := r.do(func(ctx context.Context) error {
+					return c.mkdirLocal(ctx, localItemPath)
+				})
+				if err == nil {
+					downloadDir(remoteItemPath, localItemPath)
+				}
+			} else {
+				downloads = append(downloads, download{remoteItemPath, localItemPath})
+				total += item.Size
+			}
+		}
+	}
+	downloadDir(remotePath, localPath)
+	c.plan(len(downloads), total)
+	for _, d := range downloads {
+		r.run(func(ctx context.Context) error {
+			if err := c.downloadFile(ctx, d.remotePath, d.localPath, downloadReason(d.localPath)); err != nil {
+				return fmt.Errorf("failed to download %s: %w", d.remotePath, err)
+			}
+			return nil
+		})
+	}
+	if err := r.wait(); err != nil {
+		return err
+	}
+	c.logf(ctx, "Directory download complete.\n")
+	return nil
+}
+
+// DownloadZip downloads remotePath to localPath. Directories are fetched as a
+// single zip archive built by the server and ".zip" is appended to localPath
+// if missing. If localPath is an existing directory the item is placed inside it.
+func (c *Client) DownloadZip(ctx context.Context, remotePath, localPath string) error {
+	// Check if the provided localPath exists and is a directory
+	info, err := os.Stat(localPath)
+	if err == nil && info.IsDir() {
+		// It's a directory. Construct the new path to save the file inside it.
+		baseName := filepath.Base(remotePath)
+		localPath = filepath.Join(localPath, baseName)
+	} else if err != nil && !os.IsNotExist(err) {
+		// It's some other error with the local path (e.g., permission denied)
+		return fmt.Errorf("error accessing local path %s: %w", localPath, err)
+	}
+
+	isDir, err := c.IsDir(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	// If downloading a directory, ensure .zip suffix
+	if isDir && !strings.HasSuffix(localPath, ".zip") {
+		localPath += ".zip"
+	}
+
+	var downloadURL string
+	var headers map[string]string
+
+	if isDir {
+		c.logf(ctx, "Downloading directory '%s' as a zip file to '%s'...\n", remotePath, localPath)
+		downloadURL = "/api/raw" + encodePathPreserveSlash(remotePath) + "?action=download&format=zip"
+		headers = map[string]string{"Accept": "application/zip"}
+	} else {
+		c.logf(ctx, "Downloading file '%s' to '%s'...\n", remotePath, localPath)
+		downloadURL = "/api/raw" + encodePathPreserveSlash(remotePath)
+		headers = nil // Default headers
+	}
+
+	resp, err := c.apiRequest(ctx, "GET", downloadURL, nil, headers)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("download failed: %w", newAPIError(resp))
+	}
+
+	c.plan(1, resp.ContentLength)
+	progress := c.startFile(remotePath, resp.ContentLength)
+	err = saveFile(localPath, resp.Body, progress)
+	progress.done(err)
+	if err != nil {
+		return err
+	}
+	c.logf(ctx, "Download complete.\n")
+	return nil
+}
+
+// downloadReason says whether a download to localPath adds or replaces a
+// file, for the plan of a dry run
+func downloadReason(localPath string) string {
+	if _, err := os.Stat(localPath); err == nil {
+		return "replaces the local file"
+	}
+	return "missing locally"
+}
+
+// mkdirLocal creates the local directory dir and its parents, or reports
+// them in a dry run
+func (c *Client) mkdirLocal(ctx context.Context, dir string) error {
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return nil
+	}
+	if c.Config.DryRun {
+		c.planf(ctx, "Would create local directory %s\n", dir)
+	} else if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	c.changed(Change{Action: "mkdir", Path: dir, Local: true})
+	return nil
+}
+
+// downloadFile downloads remotePath to localPath; reason says why, for the
+// plan of a dry run
+func (c *Client) downloadFile(ctx context.Context, remotePath, localPath, reason string) (err error) {
+	if c.Config.DryRun {
+		c.planf(ctx, "Would download %s to %s (%s)\n", remotePath, localPath, reason)
+		c.changed(Change{Action: "download", Path: localPath, Source: remotePath, Local: true, Reason: reason})
+		return nil
+	}
+	c.logf(ctx, "Downloading file '%s' to '%s'\n", remotePath, localPath)
+	progress := c.startFile(remotePath, -1)
+	defer func() {
+		progress.done(err)
+	}()
+	localDir := filepath.Dir(localPath)
+	if err := os.MkdirAll(localDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create parent directory %s: %w", localDir, err)
+	}
+
+	// Bytes left by an earlier run may be stale, so a resumed download is
+	// checked against the server's checksum before it is put in place
+	partPath := localPath + partialSuffix
+	verify := false
+	if info, err := os.Stat(partPath); err == nil && info.Size() > 0 {
+		c.logf(ctx, "Resuming download of '%s' at byte %d\n", remotePath, info.Size())
+		progress.moved(0, info.Size())
+		verify = true
+	}
+	dl := &rangeDownload{url: "/api/raw" + encodePathPreserveSlash(remotePath), partPath: partPath, progress: progress}
+	// The range requests are sent without retries of their own, so this loop
+	// is the only one to retry: up to c.Config.Retries attempts in a row that
+	// get no further
+	var reached int64
+	for attempts, failures := 1, 0; ; attempts++ {
+		retry, err := c.fetchRange(ctx, dl)
+		if err == nil && verify {
+			err = c.verifyDownload(ctx, remotePath, partPath)
+		}
+		if errors.Is(err, errStalePartial) {
+			c.logf(ctx, "Discarding partial download of %s: %v\n", remotePath, err)
+			_ = os.Remove(partPath)
+			progress.moved(0, 0)
+			verify = false
+			dl.lastModified = ""
+			attempts = 0
+			continue
+		}
+		if err == nil {
+			if attempts > 1 {
+				c.recordRetry("GET", dl.url, attempts, nil)
+			}
+			break
+		}
+		if !retry || ctx.Err() != nil || failures >= c.Config.Retries {
+			if retry && attempts > 1 {
+				c.recordRetry("GET", dl.url, attempts, err)
+			}
+			// The partial file is kept so the next run can resume it
+			return fmt.Errorf("download of %s failed: %w", remotePath, err)
+		}
+		// Only count attempts in a row that got nowhere
+		if info, statErr := os.Stat(partPath); statErr == nil && info.Size() > reached {
+			reached, failures = info.Size(), 0
+		}
+		failures++
+		delay := resumeDelay(err, failures)
+		c.warnf(ctx, "Download of %s interrupted (%v), resuming in %s\n", remotePath, err, formatDelay(delay))
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+
+	// A resumed download was compared with the server's checksum already
+	if c.Config.Verify && !verify {
+		localHash, err := getLocalFileHash(partPath)
+		if err != nil {
+			return fmt.Errorf("cannot verify %s: %w", remotePath, err)
+		}
+		if err := c.verifyChecksum(ctx, remotePath, localHash); err != nil {
+			if errors.Is(err, ErrChecksumMismatch) {
+				_ = os.Remove(partPath)
+			}
+			return err
+		}
+	}
+	if err := os.Rename(partPath, localPath); err != nil {
+		return fmt.Errorf("error saving downloaded file: %w", err)
+	}
+	c.logf(ctx, "Download complete.\n")
+	return nil
+}
+
+// rangeDownload is the state of a download into a partial file
+type rangeDownload struct {
+	url      string
+	partPath string
+	// lastModified identifies the version of the remote file the partial
+	// file holds, so a range is only served if the file did not change
+	lastModified string
+	progress     *fileProgress
+}
+
+// fetchRange downloads what is missing from dl.partPath, continuing after
+// the bytes it already holds. retry reports whether another attempt could
+// get further, e.g. because the connection dropped midway or the server was
+// briefly unavailable.
+func (c *Client) fetchRange(ctx context.Context, dl *rangeDownload) (retry bool, err error) {
+	var offset int64
+	if info, err := os.Stat(dl.partPath); err == nil {
+		offset = info.Size()
+	}
+	var headers map[string]string
+	if offset > 0 {
+		headers = map[string]string{"Range": fmt.Sprintf("bytes=%d-", offset)}
+		if dl.lastModified != "" {
+			headers["If-Range"] = dl.lastModified
+		}
+	}
+	resp, err := c.apiAttempt(ctx, "GET", dl.url, nil, headers)
+	if err != nil {
+		var aerr *attemptError
+		return errors.As(err, &aerr), err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	var total int64
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return false, fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+		total = size
+	case http.StatusOK:
+		// No range requested, or the file changed: start from scratch
+		flags |= os.O_TRUNC
+		total = resp.ContentLength
+		offset = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is not shorter than the remote one
+		return false, errStalePartial
+	default:
+		return false, newAPIError(resp)
+	}
+	if dl.lastModified == "" {
+		dl.lastModified = resp.Header.Get("Last-Modified")
+	}
+	if total >= 0 {
+		dl.progress.setSize(total)
+	}
+
+	out, err := os.OpenFile(dl.partPath, flags, 0o666)
+	if err != nil {
+		return false, err
+	}
+	_, err = io.Copy(dl.progress.writer(out, offset), resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return true, err
+	}
+	if total >= 0 {
+		info, err := os.Stat(dl.partPath)
+		if err != nil {
+			return false, err
+		}
+		if info.Size() != total {
+			return true, fmt.Errorf("incomplete download: got %d of %d bytes", info.Size(), total)
+		}
+	}
+	return false, nil
+}
+
+// verifyDownload compares the checksum of a downloaded file with the remote one
+func (c *Client) verifyDownload(ctx context.Context, remotePath, localPath string) error {
+	remoteHash, err := c.Checksum(ctx, remotePath, "sha256")
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w (cannot verify: %v)", errStalePartial, err)
+	}
+	localHash, err := getLocalFileHash(localPath)
+	if err != nil {
+		return fmt.Errorf("%w (cannot verify: %v)", errStalePartial, err)
+	}
+	if !sameHash(localHash, remoteHash) {
+		return errStalePartial
+	}
+	return nil
+}
+
+// parseContentRange parses "bytes start-end/size"; size is -1 if unknown
+func parseContentRange(v string) (start, size int64, ok bool) {
+	rest, found := strings.CutPrefix(v, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	byteRange, sizeStr, found := strings.Cut(rest, "/")
+	if !found {
+		return 0, 0, false
+	}
+	startStr, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	size = -1
+	if sizeStr != "*" {
+		if size, err = strconv.ParseInt(sizeStr, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, size, true
+}
+
+// saveFile writes r to localPath through a partial file that is only
+// renamed into place once complete. If the copy fails or is cancelled midway
+// the partial file is removed.
+func saveFile(localPath string, r io.Reader, progress *fileProgress) error {
+	partPath := localPath + partialSuffix
+	out, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(progress.writer(out, 0), r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partPath, localPath)
+	}
+	if err != nil {
+		_ = os.Remove(partPath)
+		return fmt.Errorf("error saving downloaded file: %w", err)
+	}
+	return nil
+}
+
+// Open streams the content of the remote file remotePath, starting at byte
+// offset and stopping after length bytes, or at the end of the file if length
+// is negative. A negative offset counts from the end of the file. size is the
+// size of the whole file, or -1 if the server did not tell. An offset past
+// the end yields no content. The caller must close r.
+func (c *Client) Open(ctx context.Context, remotePath string, offset, length int64) (r io.ReadCloser, size int64, err error) {
+	isDir, err := c.IsDir(ctx, remotePath)
+	if err != nil {
+		return nil, -1, err
+	}
+	if isDir {
+		return nil, -1, fmt.Errorf("'%s' is a directory", remotePath)
+	}
+	var headers map[string]string
+	switch {
+	case offset < 0:
+		headers = map[string]string{"Range": fmt.Sprintf("bytes=%d", offset)}
+	case length > 0:
+		headers = map[string]string{"Range": fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)}
+	case offset > 0:
+		headers = map[string]string{"Range": fmt.Sprintf("bytes=%d-", offset)}
+	}
+	resp, err := c.apiRequest(ctx, "GET", "/api/raw"+encodePathPreserveSlash(remotePath), nil, headers)
+	if err != nil {
+		return nil, -1, err
+	}
+	body := resp.Body
+	size = -1
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); ok {
+			size = total
+		}
+	case http.StatusOK:
+		// The server sent the whole file: skip to offset ourselves
+		size = resp.ContentLength
+		skip := offset
+		if offset < 0 {
+			if size < 0 {
+				_ = body.Close()
+				return nil, -1, fmt.Errorf("cannot read the end of %s: the server ignored the range", remotePath)
+			}
+			skip = max(size+offset, 0)
+		}
+		if _, err := io.CopyN(io.Discard, body, skip); err != nil && err != io.EOF {
+			_ = body.Close()
+			return nil, -1, err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// offset is at or past the end of the file
+		_ = body.Close()
+		return io.NopCloser(strings.NewReader("")), -1, nil
+	default:
+		defer func() {
+			_ = body.Close()
+		}()
+		return nil, -1, fmt.Errorf("failed to read %s: %w", remotePath, newAPIError(resp))
+	}
+	if length >= 0 {
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(body, length), body}, size, nil
+	}
+	return body, size, nil
+}
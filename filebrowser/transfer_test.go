@@ -0,0 +1,28 @@
+package filebrowser
+
+import "testing"
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value       string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 0-99/1000", 0, 1000, true},
+		{"bytes 500-999/1000", 500, 1000, true},
+		{"bytes 500-999/*", 500, -1, true},
+		{"bytes */1000", 0, 0, false},
+		{"bytes 500/1000", 0, 0, false},
+		{"bytes 500-999", 0, 0, false},
+		{"bytes x-999/1000", 0, 0, false},
+		{"bytes 500-999/big", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, size, ok := parseContentRange(tt.value)
+		if start != tt.start || size != tt.size || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v", tt.value, start, size, ok, tt.start, tt.size, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package filebrowser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// DefaultChunkSize is the chunk size of resumable uploads when
+// Config.ChunkSize is not set.
+const DefaultChunkSize = 10 << 20 // 10MB
+
+// tusVersion is the TUS protocol version spoken with /api/tus
+const tusVersion = "1.0.0"
+
+// errTusUnsupported means the server has no /api/tus endpoint
+var errTusUnsupported = errors.New("resumable uploads not supported by the server")
+
+// errTusNeedsLength means the server only takes resumable uploads whose
+// length is known when they start
+var errTusNeedsLength = errors.New("server needs the length of resumable uploads up front")
+
+// uploadChunked uploads file, which is size bytes long, to remoteFile
+// through File Browser's TUS endpoint in chunks of c.Config.ChunkSize. An
+// upload interrupted earlier is resumed if the part already on the server
+// matches the local file. A failed chunk is retried from the offset the
+// server reports, up to c.Config.Retries times in a row; the chunks are sent
+// without retries of their own.
+func (c *Client) uploadChunked(ctx context.Context, file *os.File, size int64, remoteFile string, progress *fileProgress) error {
+	tusURL := "/api/tus" + encodePathPreserveSlash(remoteFile)
+	offset := c.resumeOffset(ctx, file, size, remoteFile, tusURL)
+	if offset > 0 {
+		c.logf(ctx, "Resuming upload of %s at byte %d of %d\n", remoteFile, offset, size)
+		progress.moved(0, offset)
+	} else if err := c.tusCreate(ctx, tusURL, size); err != nil {
+		return err
+	}
+	return c.tusSend(ctx, tusURL, remoteFile, file, 0, offset, size, -1, progress)
+}
+
+// uploadStream uploads first, the first chunk of the input, and what r
+// yields after it to remoteFile through the TUS endpoint, declaring the
+// length once r is exhausted. r is read one chunk ahead, and every chunk is
+// kept until the server has it, so a failed chunk can be sent again.
+func (c *Client) uploadStream(ctx context.Context, first []byte, r io.Reader, remoteFile string, progress *fileProgress) error {
+	tusURL := "/api/tus" + encodePathPreserveSlash(remoteFile)
+	if err := c.tusCreate(ctx, tusURL, -1); err != nil {
+		return err
+	}
+	chunk, next := first, make([]byte, len(first))
+	var offset int64
+	length := int64(-1)
+	for len(chunk) > 0 {
+		n := 0
+		if length < 0 {
+			var err error
+			n, err = io.ReadFull(r, next)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				length = offset + int64(len(chunk)+n)
+				progress.setSize(length)
+			} else if err != nil {
+				return fmt.Errorf("failed to read input: %w", err)
+			}
+		}
+		end := offset + int64(len(chunk))
+		if err := c.tusSend(ctx, tusURL, remoteFile, bytes.NewReader(chunk), offset, offset, end, length, progress); err != nil {
+			return err
+		}
+		offset = end
+		chunk, next = next[:n], chunk[:cap(chunk)]
+	}
+	return nil
+}
+
+// tusSend sends the bytes from offset up to end of an upload in chunks of
+// c.Config.ChunkSize. src holds them, starting with the byte at base. A
+// failed chunk is retried from the offset the server reports, up to
+// c.Config.Retries times in a row. length is declared with every chunk
+// unless it is negative.
+func (c *Client) tusSend(ctx context.Context, tusURL, remoteFile string, src io.ReaderAt, base, offset, end, length int64, progress *fileProgress) error {
+	chunkSize := c.Config.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	failures := 0
+	for offset < end {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := min(chunkSize, end-offset)
+		chunk := progress.reader(io.NewSectionReader(src, offset-base, n), offset, n)
+		next, err := c.tusPatch(ctx, tusURL, chunk, offset, length)
+		if err == nil {
+			if failures > 0 {
+				c.recordRetry("PATCH", tusURL, failures+1, nil)
+			}
+			offset, failures = next, 0
+			continue
+		}
+		if ctx.Err() != nil || failures >= c.Config.Retries {
+			if failures > 0 {
+				c.recordRetry("PATCH", tusURL, failures+1, err)
+			}
+			return fmt.Errorf("upload of %s failed at byte %d: %w", remoteFile, offset, err)
+		}
+		failures++
+		delay := resumeDelay(err, failures)
+		c.warnf(ctx, "Chunk at byte %d of %s failed (%v), resuming in %s\n", offset, remoteFile, err, formatDelay(delay))
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+		// The chunk may have been partially written; continue where the server is
+		if offset, _, err = c.tusOffset(ctx, tusURL); err != nil {
+			return fmt.Errorf("upload of %s cannot be resumed: %w", remoteFile, err)
+		}
+		if offset < base || offset > end {
+			return fmt.Errorf("upload of %s cannot be resumed from byte %d", remoteFile, offset)
+		}
+		progress.moved(0, offset)
+	}
+	return nil
+}
+
+// resumeOffset returns where an earlier, interrupted upload of file to
+// remoteFile left off, or 0 if there is none or the bytes on the server
+// differ from the start of the local file.
+func (c *Client) resumeOffset(ctx context.Context, file *os.File, size int64, remoteFile, tusURL string) int64 {
+	offset, length, err := c.tusOffset(ctx, tusURL)
+	// Older servers report the upload length as -1
+	if err != nil || (length > 0 && length != size) || offset <= 0 || offset >= size {
+		return 0
+	}
+	remoteHash, err := c.Checksum(ctx, remoteFile, "sha256")
+	if err != nil {
+		return 0
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, offset)); err != nil {
+		return 0
+	}
+	if !sameHash(hex.EncodeToString(hash.Sum(nil)), remoteHash) {
+		c.logf(ctx, "Partial upload of %s does not match the local file, starting over.\n", remoteFile)
+		return 0
+	}
+	return offset
+}
+
+// tusCreate creates (or truncates) the remote file for an upload of size
+// bytes, or of a length declared later if size is negative
+func (c *Client) tusCreate(ctx context.Context, tusURL string, size int64) error {
+	headers := map[string]string{"Tus-Resumable": tusVersion}
+	if size >= 0 {
+		headers["Upload-Length"] = strconv.FormatInt(size, 10)
+	} else {
+		headers["Upload-Defer-Length"] = "1"
+	}
+	resp, err := c.apiRequest(ctx, "POST", tusURL+"?override=true", nil, headers)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return errTusUnsupported
+	case http.StatusBadRequest:
+		if size < 0 {
+			return errTusNeedsLength
+		}
+	}
+	return fmt.Errorf("failed to start upload: %w", newAPIError(resp))
+}
+
+// tusOffset asks the server how many bytes of an upload it has and how long
+// the upload is going to be (-1 or 0 if it does not say)
+func (c *Client) tusOffset(ctx context.Context, tusURL string) (offset, length int64, err error) {
+	headers := map[string]string{"Tus-Resumable": tusVersion}
+	resp, err := c.apiRequest(ctx, "HEAD", tusURL, nil, headers)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return 0, 0, newAPIError(resp)
+	}
+	offset, err = strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Upload-Offset %q", resp.Header.Get("Upload-Offset"))
+	}
+	length, _ = strconv.ParseInt(resp.Header.Get("Upload-Length"), 10, 64)
+	return offset, length, nil
+}
+
+// tusPatch sends one chunk starting at offset and returns the new offset;
+// length is the upload length to declare, or negative
+func (c *Client) tusPatch(ctx context.Context, tusURL string, chunk *progressReader, offset, length int64) (int64, error) {
+	headers := map[string]string{
+		"Tus-Resumable": tusVersion,
+		"Upload-Offset": strconv.FormatInt(offset, 10),
+		"Content-Type":  "application/offset+octet-stream",
+	}
+	if length >= 0 {
+		headers["Upload-Length"] = strconv.FormatInt(length, 10)
+	}
+	resp, err := c.apiAttempt(ctx, "PATCH", tusURL, chunk, headers)
+	if err != nil {
+		return offset, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return offset, newAPIError(resp)
+	}
+	next, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return offset + chunk.Size(), nil
+	}
+	return next, nil
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// findOptions are the tests and actions of find
+type findOptions struct {
+	tests    []func(*treeNode) bool
+	minDepth int
+	maxDepth int // -1 for no limit
+	print    bool
+	print0   bool
+	delete   bool
+	exec     []findExec
+}
+
+// findExec is a command run by -exec for every match (";") or once for
+// all of them ("+"); "{}" in args stands for the remote path
+type findExec struct {
+	args  []string
+	batch bool
+}
+
+// matchRecord is a remote file or directory found by find
+type matchRecord struct {
+	Type string `json:"type"`
+	entryRecord
+}
+
+// parseFindArgs separates the starting paths of find from its expression:
+// the tests -name, -iname, -path, -regex, -type, -size, -mtime, -mmin,
+// -mindepth and -maxdepth, all of which must match, and the actions -print,
+// -print0, -delete and -exec
+func parseFindArgs(args []string) (findOptions, []string, error) {
+	opts := findOptions{maxDepth: -1}
+	var paths []string
+	i := 0
+	for ; i < len(args) && !strings.HasPrefix(args[i], "-"); i++ {
+		paths = append(paths, args[i])
+	}
+	for ; i < len(args); i++ {
+		name := args[i]
+		switch name {
+		case "-print":
+			opts.print = true
+			continue
+		case "-print0":
+			opts.print0 = true
+			continue
+		case "-delete":
+			opts.delete = true
+			continue
+		case "-exec":
+			end := i + 1
+			for end < len(args) && args[end] != ";" && args[end] != "+" {
+				end++
+			}
+			if end >= len(args) || end == i+1 {
+				return opts, nil, fmt.Errorf("-exec requires a command ending in ';' or '+'")
+			}
+			x := findExec{args: args[i+1 : end], batch: args[end] == "+"}
+			if x.batch && (len(x.args) < 2 || x.args[len(x.args)-1] != "{}") {
+				return opts, nil, fmt.Errorf("-exec ... + requires {} right before the '+'")
+			}
+			opts.exec = append(opts.exec, x)
+			i = end
+			continue
+		}
+		if i+1 >= len(args) {
+			return opts, nil, fmt.Errorf("unknown find option %s or missing value", name)
+		}
+		i++
+		value := args[i]
+		var test func(*treeNode) bool
+		switch name {
+		case "-name", "-iname":
+			pattern := value
+			if name == "-iname" {
+				pattern = strings.ToLower(pattern)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return opts, nil, fmt.Errorf("invalid pattern %q: %w", value, err)
+			}
+			test = func(n *treeNode) bool {
+				base := path.Base(n.path)
+				if name == "-iname" {
+					base = strings.ToLower(base)
+				}
+				ok, _ := path.Match(pattern, base)
+				return ok
+			}
+		case "-path":
+			re, err := globRegexp(value)
+			if err != nil {
+				return opts, nil, fmt.Errorf("invalid pattern %q: %w", value, err)
+			}
+			test = func(n *treeNode) bool { return re.MatchString(n.path) }
+		case "-regex":
+			// Like find, the expression has to match the whole path
+			re, err := regexp.Compile("^(?:" + value + ")$")
+			if err != nil {
+				return opts, nil, fmt.Errorf("invalid regex %q: %w", value, err)
+			}
+			test = func(n *treeNode) bool { return re.MatchString(n.path) }
+		case "-type":
+			switch value {
+			case "f":
+				test = func(n *treeNode) bool { return !n.item.IsDir }
+			case "d":
+				test = func(n *treeNode) bool { return n.item.IsDir }
+			default:
+				return opts, nil, fmt.Errorf("invalid type %q (want f or d)", value)
+			}
+		case "-size":
+			sign, v := splitSign(value)
+			size, err := parseSize(v)
+			if err != nil {
+				return opts, nil, err
+			}
+			test = func(n *treeNode) bool { return compareSign(sign, n.item.Size, size) }
+		case "-mtime", "-mmin":
+			sign, v := splitSign(value)
+			count, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || count < 0 {
+				return opts, nil, fmt.Errorf("invalid %s value %q", name, value)
+			}
+			unit := 24 * time.Hour
+			if name == "-mmin" {
+				unit = time.Minute
+			}
+			now := time.Now()
+			test = func(n *treeNode) bool {
+				modified, err := time.Parse(time.RFC3339, n.item.Modified)
+				if err != nil {
+					return false
+				}
+				// Ages are counted in whole units, rounded down, as by find
+				return compareSign(sign, int64(now.Sub(modified)/unit), count)
+			}
+		case "-mindepth", "-maxdepth":
+			depth, err := strconv.Atoi(value)
+			if err != nil || depth < 0 {
+				return opts, nil, fmt.Errorf("invalid depth %q", value)
+			}
+			if name == "-mindepth" {
+				opts.minDepth = depth
+			} else {
+				opts.maxDepth = depth
+			}
+			continue
+		default:
+			return opts, nil, fmt.Errorf("unknown find option %s", name)
+		}
+		opts.tests = append(opts.tests, test)
+	}
+	if !opts.print0 && !opts.delete && len(opts.exec) == 0 {
+		opts.print = true
+	}
+	return opts, paths, nil
+}
+
+// globRegexp turns a glob into a regexp matching whole paths in which, as
+// for find -path, "*" and "?" also match "/"
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, path.ErrBadPattern
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// splitSign splits the "+" (more than) or "-" (less than) off a numeric
+// argument of find
+func splitSign(v string) (byte, string) {
+	if v != "" && (v[0] == '+' || v[0] == '-') {
+		return v[0], v[1:]
+	}
+	return 0, v
+}
+
+// compareSign tells whether n is more than (+), less than (-) or exactly
+// limit
+func compareSign(sign byte, n, limit int64) bool {
+	switch sign {
+	case '+':
+		return n > limit
+	case '-':
+		return n < limit
+	}
+	return n == limit
+}
+
+// findPaths walks remote trees like find, testing every entry including the
+// starting paths, and runs the actions on the entries that match: printing
+// their paths (NUL-terminated with -print0, as records in JSON mode),
+// running -exec commands on the local machine and, with -delete, deleting
+// them. Directories are deleted only if everything in them matched as well.
+func findPaths(ctx context.Context, client *filebrowser.Client, remotePaths []string, ignoreRegex *regexp.Regexp, opts findOptions) error {
+	var roots []*treeNode
+	for _, remotePath := range remotePaths {
+		item, err := client.Stat(ctx, remotePath)
+		if err != nil {
+			return err
+		}
+		root := &treeNode{item: *item, path: path.Clean("/" + remotePath)}
+		if item.IsDir && opts.maxDepth != 0 {
+			root.children, root.ignored, err = fetchTree(ctx, client, root.path, ignoreRegex, treeOptions{depth: max(opts.maxDepth, 0)}, 1)
+			if err != nil {
+				return err
+			}
+		}
+		roots = append(roots, root)
+	}
+
+	matched := map[*treeNode]bool{}
+	var matches []string
+	listFailed, execFailed := 0, 0
+	var visit func(n *treeNode, depth int)
+	visit = func(n *treeNode, depth int) {
+		if n.err != nil {
+			listFailed++
+			warnList(n)
+		}
+		if depth >= opts.minDepth && findMatches(n, opts.tests) {
+			matched[n] = true
+			matches = append(matches, n.path)
+			switch {
+			case jsonOut != nil && (opts.print || opts.print0):
+				jsonOut.record(matchRecord{Type: "match", entryRecord: newEntryRecord(path.Dir(n.path), n.item)})
+			case opts.print0:
+				fmt.Printf("%s\x00", n.path)
+			case opts.print:
+				fmt.Println(n.path)
+			}
+			for _, x := range opts.exec {
+				if !x.batch && !runExec(client, x, []string{n.path}) {
+					execFailed++
+				}
+			}
+		}
+		for _, c := range n.children {
+			visit(c, depth+1)
+		}
+	}
+	for _, root := range roots {
+		visit(root, 0)
+	}
+
+	for _, x := range opts.exec {
+		if x.batch && len(matches) > 0 && !runExec(client, x, matches) {
+			execFailed++
+		}
+	}
+	if opts.delete {
+		var targets []string
+		for _, root := range roots {
+			deletable(root, matched, &targets)
+		}
+		for _, target := range targets {
+			if err := client.Delete(ctx, target); err != nil {
+				return err
+			}
+		}
+	}
+	if listFailed > 0 {
+		return fmt.Errorf("%s could not be listed", plural(listFailed, "directory", "directories"))
+	}
+	if execFailed > 0 {
+		return fmt.Errorf("%s failed", plural(execFailed, "-exec command", "-exec commands"))
+	}
+	return nil
+}
+
+// findMatches tells whether n passes every test
+func findMatches(n *treeNode, tests []func(*treeNode) bool) bool {
+	for _, test := range tests {
+		if !test(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// runExec runs an -exec command with {} replaced by paths, or in a dry run
+// prints it; it reports whether the command succeeded
+func runExec(client *filebrowser.Client, x findExec, paths []string) bool {
+	var argv []string
+	for _, arg := range x.args {
+		if arg == "{}" {
+			argv = append(argv, paths...)
+		} else {
+			argv = append(argv, strings.ReplaceAll(arg, "{}", paths[0]))
+		}
+	}
+	if client.Config.DryRun {
+		if jsonOut == nil {
+			fmt.Printf("Would run %s\n", strings.Join(argv, " "))
+		}
+		return true
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if jsonOut != nil {
+			jsonOut.message(messageRecord{Type: "error", Message: fmt.Sprintf("%s: %v", argv[0], err)})
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", argv[0], err)
+		}
+		return false
+	}
+	return true
+}
+
+// deletable collects in targets the entries of the tree below n that
+// -delete removes and tells whether n is one of them. A directory goes only if
+// everything in it matched too, so -delete never takes along anything that
+// did not, including entries the ignore pattern left out; it is then deleted
+// in one go with its contents.
+func deletable(n *treeNode, matched map[*treeNode]bool, targets *[]string) bool {
+	empty := !n.item.IsDir || (n.children != nil && n.err == nil && n.ignored == 0)
+	var below []string
+	for _, c := range n.children {
+		empty = deletable(c, matched, &below) && empty
+	}
+	if matched[n] && empty {
+		*targets = append(*targets, n.path)
+		return true
+	}
+	*targets = append(*targets, below...)
+	if matched[n] {
+		if jsonOut != nil {
+			jsonOut.message(messageRecord{Type: "warning", Message: fmt.Sprintf("not deleting %s: directory not empty", n.path)})
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: not deleting %s: directory not empty\n", n.path)
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+	"golang.org/x/term"
+)
+
+const (
+	colorBlue  = "\033[1;34m"
+	colorReset = "\033[0m"
+)
+
+// listEntries fetches a remote directory, drops blank/ghost entries and entries
+// matching ignoreRegex, deduplicates by normalized name (keeping the most
+// recent) and sorts by Modified descending, then by Name.
+func listEntries(ctx context.Context, client *filebrowser.Client, remotePath string, ignoreRegex *regexp.Regexp) ([]filebrowser.RemoteItem, error) {
+	items, err := client.List(ctx, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	dedup := make(map[string]filebrowser.RemoteItem)
+	for _, item := range items {
+		norm := strings.TrimRight(item.Name, "\r\n")
+		if strings.TrimSpace(norm) == "" {
+			continue // skip blank/ghost entries after normalization
+		}
+		if ignoreRegex != nil && shouldIgnoreRegex(norm, ignoreRegex) {
+			continue
+		}
+		if e, ok := dedup[norm]; !ok || item.Modified > e.Modified {
+			dedup[norm] = item
+		}
+	}
+	sorted := make([]filebrowser.RemoteItem, 0, len(dedup))
+	for _, v := range dedup {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Modified == sorted[j].Modified {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Modified > sorted[j].Modified
+	})
+	return sorted, nil
+}
+
+// displayName strips newlines from an entry name and marks directories with a trailing slash
+func displayName(e filebrowser.RemoteItem) string {
+	name := e.Name
+	name = strings.ReplaceAll(name, "\n", "")
+	name = strings.ReplaceAll(name, "\r", "")
+	if e.IsDir && !strings.HasSuffix(name, "/") {
+		name += "/"
+	}
+	return name
+}
+
+// listNames prints a directory like ls: multi-column with colors, or one
+// name per line without colors in script mode
+func listNames(ctx context.Context, client *filebrowser.Client, remotePath string, ignoreRegex *regexp.Regexp, scriptMode bool) error {
+	sorted, err := listEntries(ctx, client, remotePath, ignoreRegex)
+	if err != nil {
+		return err
+	}
+	printNames(sorted, scriptMode)
+	return nil
+}
+
+// printNames prints directory entries for listNames
+func printNames(sorted []filebrowser.RemoteItem, scriptMode bool) {
+	if scriptMode {
+		// Script-friendly mode: one name per line, no colors, no formatting
+		for _, e := range sorted {
+			fmt.Println(displayName(e))
+		}
+		return
+	}
+
+	// Human-friendly mode: multi-column with colors
+	// Prepare names and calculate max width
+	names := make([]string, len(sorted))
+	maxLen := 0
+	for i, e := range sorted {
+		name := displayName(e)
+		if e.IsDir {
+			name = colorBlue + name + colorReset
+		}
+		names[i] = name
+		// Visible length (strip color codes for width)
+		visible := len([]rune(stripANSICodes(name)))
+		if visible > maxLen {
+			maxLen = visible
+		}
+	}
+	if maxLen < 16 {
+		maxLen = 16
+	}
+	// Get terminal width (default 80)
+	width := 80
+	if w, ok := getTerminalWidth(); ok {
+		width = w
+	}
+	colWidth := maxLen + 2
+	cols := width / colWidth
+	if cols < 1 {
+		cols = 1
+	}
+	rows := (len(names) + cols - 1) / cols
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			i := c*rows + r
+			if i >= len(names) {
+				continue
+			}
+			name := names[i]
+			fmt.Printf("%-*s", colWidth+len(name)-len(stripANSICodes(name)), name)
+		}
+		fmt.Println()
+	}
+}
+
+// listDetailed prints a directory like ls -l: name, modification date and size
+func listDetailed(ctx context.Context, client *filebrowser.Client, remotePath string, ignoreRegex *regexp.Regexp) error {
+	sorted, err := listEntries(ctx, client, remotePath, ignoreRegex)
+	if err != nil {
+		return err
+	}
+	printDetailed(sorted)
+	return nil
+}
+
+// printDetailed prints directory entries for listDetailed
+func printDetailed(sorted []filebrowser.RemoteItem) {
+	maxName := 4 // min width for 'Name'
+	for _, e := range sorted {
+		if l := len(e.Name); l > maxName {
+			maxName = l
+		}
+	}
+	if maxName > 60 {
+		maxName = 60
+	} else if maxName < 30 {
+		maxName = 30
+	}
+	fmt.Printf("%-*s %-19s %-8s\n", maxName, "Name", "Modified", "Size")
+	fmt.Printf("%-*s %-19s %-8s\n", maxName, strings.Repeat("-", maxName), strings.Repeat("-", 19), strings.Repeat("-", 8))
+	for _, e := range sorted {
+		name := displayName(e)
+		date := formatModified(e.Modified)
+		if e.IsDir {
+			pad := maxName - len([]rune(name))
+			if pad < 0 {
+				pad = 0
+			}
+			fmt.Printf("%s%s %-19s %-8d\n", colorBlue+name+colorReset, strings.Repeat(" ", pad), date, e.Size)
+		} else {
+			fmt.Printf("%-*s %-19s %-8d\n", maxName, name, date, e.Size)
+		}
+	}
+}
+
+// listJSON prints a directory as a JSON array of its entries
+func listJSON(ctx context.Context, client *filebrowser.Client, remotePath string, ignoreRegex *regexp.Regexp) error {
+	sorted, err := listEntries(ctx, client, remotePath, ignoreRegex)
+	if err != nil {
+		return err
+	}
+	entries := make([]entryRecord, 0, len(sorted))
+	for _, e := range sorted {
+		entries = append(entries, newEntryRecord(remotePath, e))
+	}
+	jsonOut.record(entries)
+	return nil
+}
+
+// formatModified formats an API timestamp like bash: YYYY-MM-DD HH:MM:SS
+func formatModified(date string) string {
+	if len(date) > 19 && strings.Contains(date, "T") {
+		return strings.Replace(date[:19], "T", " ", 1)
+	} else if len(date) > 19 {
+		return date[:19]
+	}
+	return date
+}
+
+// stripANSICodes removes ANSI escape sequences from a string
+func stripANSICodes(s string) string {
+	res := make([]rune, 0, len(s))
+	inEsc := false
+	for _, r := range s {
+		if r == '\033' {
+			inEsc = true
+			continue
+		}
+		if inEsc {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEsc = false
+			}
+			continue
+		}
+		res = append(res, r)
+	}
+	return string(res)
+}
+
+// getTerminalWidth tries to get the terminal width, returns (width, ok)
+func getTerminalWidth() (int, bool) {
+	// Try golang.org/x/term for portable terminal width
+	fd := int(os.Stdout.Fd())
+	width, _, err := term.GetSize(fd)
+	if err == nil && width > 0 {
+		return width, true
+	}
+	// Fallback to COLUMNS env
+	if colStr := os.Getenv("COLUMNS"); colStr != "" {
+		var cols int
+		_, err := fmt.Sscanf(colStr, "%d", &cols)
+		if err == nil && cols > 0 {
+			return cols, true
+		}
+	}
+	return 80, false
+}
+
+// shouldIgnoreRegex returns true if name matches the ignore regex
+func shouldIgnoreRegex(name string, ignoreRegex *regexp.Regexp) bool {
+	return ignoreRegex.MatchString(name)
+}
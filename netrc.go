@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// netrcEntry is a machine (or default) entry of a .netrc file
+type netrcEntry struct {
+	Machine  string
+	Login    string
+	Password string
+}
+
+// netrcPath returns $NETRC, or ~/.netrc (~/_netrc on Windows)
+func netrcPath() string {
+	if env := os.Getenv("NETRC"); env != "" {
+		return env
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+	return filepath.Join(home, name)
+}
+
+// parseNetrc parses the machine and default entries of a .netrc file.
+// Macro definitions are skipped; the default entry has an empty Machine.
+func parseNetrc(data string) []netrcEntry {
+	var entries []netrcEntry
+	var cur *netrcEntry
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			if strings.HasPrefix(fields[j], "#") {
+				break
+			}
+			next := func() string {
+				if j+1 < len(fields) {
+					j++
+					return fields[j]
+				}
+				return ""
+			}
+			switch fields[j] {
+			case "machine":
+				entries = append(entries, netrcEntry{Machine: next()})
+				cur = &entries[len(entries)-1]
+			case "default":
+				entries = append(entries, netrcEntry{})
+				cur = &entries[len(entries)-1]
+			case "login":
+				if value := next(); cur != nil {
+					cur.Login = value
+				}
+			case "password":
+				if value := next(); cur != nil {
+					cur.Password = value
+				}
+			case "account":
+				next()
+			case "macdef":
+				// A macro runs until the next empty line
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	return entries
+}
+
+// lookupNetrc returns the .netrc entry for the host of serverURL. When
+// username is not empty only an entry with that login matches. A machine
+// entry is preferred over the default entry.
+func lookupNetrc(serverURL, username string) (netrcEntry, bool) {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Hostname() == "" {
+		return netrcEntry{}, false
+	}
+	path := netrcPath()
+	if path == "" {
+		return netrcEntry{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return netrcEntry{}, false
+	}
+	var fallback *netrcEntry
+	entries := parseNetrc(string(data))
+	for i, e := range entries {
+		if username != "" && e.Login != username {
+			continue
+		}
+		if e.Machine == "" {
+			if fallback == nil {
+				fallback = &entries[i]
+			}
+			continue
+		}
+		if strings.EqualFold(e.Machine, u.Hostname()) || strings.EqualFold(e.Machine, u.Host) {
+			return e, true
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return netrcEntry{}, false
+}
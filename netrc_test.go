@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	data := `# servers
+machine files.example.com login alice password s3cret
+machine nas.local
+  login bob
+  account ignored
+  password hunter2
+
+macdef init
+machine evil.example.com login mallory password nope
+cd /tmp
+
+default login guest password guest # fallback
+`
+	want := []netrcEntry{
+		{Machine: "files.example.com", Login: "alice", Password: "s3cret"},
+		{Machine: "nas.local", Login: "bob", Password: "hunter2"},
+		{Login: "guest", Password: "guest"},
+	}
+	if got := parseNetrc(data); !slices.Equal(got, want) {
+		t.Errorf("parseNetrc = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseNetrcIncomplete(t *testing.T) {
+	// Tokens before the first machine and a missing value are ignored
+	got := parseNetrc("login nobody\nmachine host login")
+	want := []netrcEntry{{Machine: "host"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseNetrc = %+v, want %+v", got, want)
+	}
+}
+
+func TestLookupNetrc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netrc")
+	data := `default login guest password guest
+machine files.example.com login alice password a
+machine files.example.com login bob password b
+machine nas.local:8080 login carol password c
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NETRC", path)
+	tests := []struct {
+		url, username string
+		login         string
+		ok            bool
+	}{
+		{"https://files.example.com", "", "alice", true},
+		{"https://FILES.example.com/fb", "bob", "bob", true},
+		{"http://nas.local:8080", "", "carol", true},
+		{"https://other.example.com", "", "guest", true},
+		{"https://files.example.com", "dave", "", false},
+		{"not a url", "", "", false},
+	}
+	for _, tt := range tests {
+		e, ok := lookupNetrc(tt.url, tt.username)
+		if ok != tt.ok || e.Login != tt.login {
+			t.Errorf("lookupNetrc(%q, %q) = %+v, %v; want login %q, %v", tt.url, tt.username, e, ok, tt.login, tt.ok)
+		}
+	}
+}
+
+func TestLookupNetrcMissingFile(t *testing.T) {
+	t.Setenv("NETRC", filepath.Join(t.TempDir(), "missing"))
+	if e, ok := lookupNetrc("https://files.example.com", ""); ok {
+		t.Errorf("lookupNetrc without a file = %+v; want no entry", e)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// jsonOut is set when --output json is in effect
+var jsonOut *jsonOutput
+
+// jsonOutput writes the records of --output json, one JSON object per line:
+// results to stdout, warnings and errors to stderr
+type jsonOutput struct {
+	mu     sync.Mutex
+	stdout io.Writer
+	stderr io.Writer
+	dryRun bool
+}
+
+func newJSONOutput() *jsonOutput {
+	return &jsonOutput{stdout: os.Stdout, stderr: os.Stderr}
+}
+
+// entryRecord is a remote directory entry
+type entryRecord struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	IsDir     bool   `json:"isDir"`
+	IsSymlink bool   `json:"isSymlink"`
+	Size      int64  `json:"size"`
+	Modified  string `json:"modified"`
+	Mode      string `json:"mode"`
+	Extension string `json:"extension"`
+	FileType  string `json:"fileType"`
+}
+
+// planRecord announces the files a transfer command is going to look at
+type planRecord struct {
+	Type  string `json:"type"`
+	Files int    `json:"files"`
+	Size  int64  `json:"size"`
+}
+
+// fileRecord is the outcome of one file of a transfer command
+type fileRecord struct {
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	Bytes       int64  `json:"bytes"`
+	Transferred int64  `json:"transferred"`
+	Error       string `json:"error,omitempty"`
+}
+
+// changeRecord is a change made, or in a dry run planned, on either side
+type changeRecord struct {
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	Path   string `json:"path"`
+	Source string `json:"source,omitempty"`
+	Local  bool   `json:"local"`
+	Reason string `json:"reason,omitempty"`
+	DryRun bool   `json:"dryRun"`
+}
+
+// summaryRecord sums up a transfer command
+type summaryRecord struct {
+	Type        string  `json:"type"`
+	Transferred int     `json:"transferred"`
+	Skipped     int     `json:"skipped"`
+	Failed      int     `json:"failed"`
+	Bytes       int64   `json:"bytes"`
+	Elapsed     float64 `json:"elapsed"`
+}
+
+// messageRecord is a warning, an error of a single item or, with an exit
+// code, the error that ended the command
+type messageRecord struct {
+	Type     string   `json:"type"`
+	Message  string   `json:"message"`
+	ExitCode int      `json:"exitCode,omitempty"`
+	Hints    []string `json:"hints,omitempty"`
+}
+
+// retryRecord is a request that needed more than one attempt
+type retryRecord struct {
+	Type     string `json:"type"`
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// record writes v to stdout
+func (o *jsonOutput) record(v any) {
+	o.write(o.stdout, v)
+}
+
+// message writes v to stderr
+func (o *jsonOutput) message(v any) {
+	o.write(o.stderr, v)
+}
+
+func (o *jsonOutput) write(w io.Writer, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_, _ = w.Write(append(b, '\n'))
+}
+
+// change is the Changed callback of the client
+func (o *jsonOutput) change(ch filebrowser.Change) {
+	o.record(changeRecord{Type: "change", Action: ch.Action, Path: ch.Path, Source: ch.Source,
+		Local: ch.Local, Reason: ch.Reason, DryRun: o.dryRun})
+}
+
+// progress turns the end of a file's transfer into a record
+func (o *jsonOutput) progress(ev filebrowser.ProgressEvent) {
+	rec := fileRecord{Type: "file", Path: ev.Path, Size: ev.Size, Bytes: ev.Bytes, Transferred: ev.Transferred}
+	switch ev.Kind {
+	case filebrowser.Planned:
+		o.record(planRecord{Type: "plan", Files: ev.Files, Size: ev.Size})
+		return
+	case filebrowser.FileDone:
+		rec.Status = "done"
+	case filebrowser.FileSkipped:
+		rec.Status = "skipped"
+	case filebrowser.FileFailed:
+		rec.Status = "failed"
+		if ev.Err != nil {
+			rec.Error = ev.Err.Error()
+		}
+	default:
+		return
+	}
+	o.record(rec)
+}
+
+// messages returns the writer for the client's Err, which turns each line
+// into a warning or error record
+func (o *jsonOutput) messages() io.Writer {
+	return &messageWriter{o: o}
+}
+
+type messageWriter struct {
+	o       *jsonOutput
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (w *messageWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, b...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.partial[:i])
+		w.partial = w.partial[i+1:]
+		rec := messageRecord{Type: "warning", Message: strings.TrimPrefix(line, "Warning: ")}
+		if msg, ok := strings.CutPrefix(line, "Error: "); ok {
+			rec = messageRecord{Type: "error", Message: msg}
+		}
+		w.o.message(rec)
+	}
+	return len(b), nil
+}
+
+// newEntryRecord describes item, an entry of the remote directory dir
+func newEntryRecord(dir string, item filebrowser.RemoteItem) entryRecord {
+	p := item.Path
+	if p == "" {
+		p = path.Join("/", dir, item.Name)
+	}
+	mode := ""
+	if item.Mode != 0 {
+		mode = item.Mode.String()
+	}
+	return entryRecord{
+		Name:      item.Name,
+		Path:      p,
+		IsDir:     item.IsDir,
+		IsSymlink: item.IsSymlink,
+		Size:      item.Size,
+		Modified:  item.Modified,
+		Mode:      mode,
+		Extension: item.Extension,
+		FileType:  item.Type,
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+	"golang.org/x/term"
+)
+
+const (
+	// progressRedraw is how often the progress bars are redrawn at most
+	progressRedraw = 100 * time.Millisecond
+	// progressReport is how often progress lines are written at most when
+	// stdout is not a terminal
+	progressReport = 5 * time.Second
+	// maxProgressBars is how many per-file bars are shown at once
+	maxProgressBars  = 8
+	progressBarWidth = 20
+)
+
+// progressMeter follows the files a transfer command moves. On a terminal
+// it keeps bars for the running files and the whole command below the
+// regular output; otherwise it writes a "progress ..." line to stderr every
+// few seconds. Either way it sums up the run when the command finishes. With
+// --output json it writes a record for each file and the summary instead.
+type progressMeter struct {
+	mu     sync.Mutex
+	tty    bool
+	show   bool
+	stdout io.Writer
+	stderr io.Writer
+	start  time.Time
+
+	planned bool
+	files   int
+	size    int64 // -1 if unknown
+	active  []*fileMeter
+
+	transferred int
+	skipped     int
+	failed      int
+	// wire is what was sent or received, settled what is in place of the
+	// files that are no longer running
+	wire    int64
+	settled int64
+
+	drawn      int
+	lastDraw   time.Time
+	lastReport time.Time
+}
+
+type fileMeter struct {
+	path        string
+	size        int64
+	bytes       int64
+	transferred int64
+	start       time.Time
+	lastReport  time.Time
+}
+
+// startProgress makes client report its transfers to a new meter; show
+// false leaves out the bars and progress lines but keeps the summary
+func startProgress(client *filebrowser.Client, show bool) *progressMeter {
+	now := time.Now()
+	m := &progressMeter{
+		tty:        term.IsTerminal(int(os.Stdout.Fd())),
+		show:       show,
+		stdout:     os.Stdout,
+		stderr:     os.Stderr,
+		start:      now,
+		lastReport: now,
+	}
+	client.Progress = m.event
+	if jsonOut != nil {
+		m.show = false
+		return m
+	}
+	client.Out = meterWriter{m, os.Stdout}
+	client.Err = meterWriter{m, os.Stderr}
+	return m
+}
+
+// meterWriter keeps regular output above the progress bars
+type meterWriter struct {
+	m *progressMeter
+	w io.Writer
+}
+
+func (w meterWriter) Write(b []byte) (int, error) {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	w.m.clear()
+	n, err := w.w.Write(b)
+	w.m.draw(time.Now())
+	return n, err
+}
+
+func (m *progressMeter) event(ev filebrowser.ProgressEvent) {
+	if jsonOut != nil {
+		jsonOut.progress(ev)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	switch ev.Kind {
+	case filebrowser.Planned:
+		if !m.planned || m.size >= 0 && ev.Size >= 0 {
+			m.size += ev.Size
+		} else {
+			m.size = -1
+		}
+		m.planned = true
+		m.files += ev.Files
+	case filebrowser.FileStarted:
+		m.active = append(m.active, &fileMeter{path: ev.Path, size: ev.Size, start: now, lastReport: now})
+	case filebrowser.FileProgress:
+		f := m.file(ev.Path)
+		if f == nil {
+			return
+		}
+		m.wire += ev.Transferred - f.transferred
+		f.size, f.bytes, f.transferred = ev.Size, ev.Bytes, ev.Transferred
+		if !m.tty && m.show && now.Sub(f.lastReport) >= progressReport {
+			f.lastReport = now
+			m.report(f, now)
+		}
+	case filebrowser.FileDone, filebrowser.FileFailed, filebrowser.FileSkipped:
+		if f := m.remove(ev.Path); f != nil {
+			m.wire += ev.Transferred - f.transferred
+		}
+		m.settled += max(ev.Bytes, 0)
+		switch ev.Kind {
+		case filebrowser.FileDone:
+			m.transferred++
+		case filebrowser.FileFailed:
+			m.failed++
+			// What a failed file did not get is no longer expected
+			if m.size >= 0 && ev.Size >= 0 {
+				m.size -= ev.Size - ev.Bytes
+			}
+		default:
+			m.skipped++
+		}
+	}
+	if ev.Kind != filebrowser.FileProgress || now.Sub(m.lastDraw) >= progressRedraw {
+		m.clear()
+		m.draw(now)
+	}
+}
+
+func (m *progressMeter) file(remotePath string) *fileMeter {
+	for _, f := range m.active {
+		if f.path == remotePath {
+			return f
+		}
+	}
+	return nil
+}
+
+func (m *progressMeter) remove(remotePath string) *fileMeter {
+	for i, f := range m.active {
+		if f.path == remotePath {
+			m.active = append(m.active[:i], m.active[i+1:]...)
+			return f
+		}
+	}
+	return nil
+}
+
+// clear removes the progress bars from the terminal
+func (m *progressMeter) clear() {
+	if m.drawn > 0 {
+		fmt.Fprintf(m.stdout, "\033[%dA\033[J", m.drawn)
+		m.drawn = 0
+	}
+}
+
+// draw writes the progress bars below the cursor
+func (m *progressMeter) draw(now time.Time) {
+	if !m.tty || !m.show || !m.planned {
+		return
+	}
+	m.lastDraw = now
+	width, _ := getTerminalWidth()
+	var lines []string
+	for i, f := range m.active {
+		if i == maxProgressBars {
+			lines = append(lines, fmt.Sprintf("  ... and %d more", len(m.active)-i))
+			break
+		}
+		elapsed := now.Sub(f.start)
+		lines = append(lines, fmt.Sprintf("%s %s  %s", progressBar(f.bytes, f.size),
+			progressStats(f.bytes, f.size, f.transferred, elapsed), path.Base(f.path)))
+	}
+	done := m.transferred + m.skipped + m.failed
+	bytes, size := m.position()
+	lines = append(lines, fmt.Sprintf("%s %s  %d/%d files", progressBar(bytes, size),
+		progressStats(bytes, size, m.wire, now.Sub(m.start)), done, m.files))
+	for _, line := range lines {
+		if runes := []rune(line); len(runes) >= width {
+			line = string(runes[:width-1])
+		}
+		fmt.Fprintln(m.stdout, line)
+	}
+	m.drawn = len(lines)
+}
+
+// report writes the progress of f and of the whole command as lines of
+// key=value pairs
+func (m *progressMeter) report(f *fileMeter, now time.Time) {
+	rate, eta := rateETA(f.bytes, f.size, f.transferred, now.Sub(f.start))
+	fmt.Fprintf(m.stderr, "progress file=%q bytes=%d size=%d rate=%d eta=%d\n", f.path, f.bytes, f.size, rate, eta)
+	if now.Sub(m.lastReport) >= progressReport {
+		m.lastReport = now
+		bytes, size := m.position()
+		rate, eta := rateETA(bytes, size, m.wire, now.Sub(m.start))
+		fmt.Fprintf(m.stderr, "progress total bytes=%d size=%d files=%d/%d rate=%d eta=%d\n",
+			bytes, size, m.transferred+m.skipped+m.failed, m.files, rate, eta)
+	}
+}
+
+// position returns how much of the whole command is done and its size
+func (m *progressMeter) position() (bytes, size int64) {
+	bytes = m.settled
+	for _, f := range m.active {
+		bytes += f.bytes
+	}
+	return bytes, m.size
+}
+
+// finish removes the progress bars and prints the summary of the run
+func (m *progressMeter) finish() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clear()
+	if !m.planned && m.transferred+m.skipped+m.failed == 0 {
+		return
+	}
+	elapsed := time.Since(m.start)
+	if jsonOut != nil {
+		jsonOut.record(summaryRecord{Type: "summary", Transferred: m.transferred, Skipped: m.skipped,
+			Failed: m.failed, Bytes: m.wire, Elapsed: elapsed.Round(time.Millisecond).Seconds()})
+		return
+	}
+	if !m.tty {
+		fmt.Fprintf(m.stderr, "summary transferred=%d skipped=%d failed=%d bytes=%d elapsed=%.1f\n",
+			m.transferred, m.skipped, m.failed, m.wire, elapsed.Seconds())
+		return
+	}
+	rate, _ := rateETA(0, -1, m.wire, elapsed)
+	fmt.Fprintf(m.stdout, "Summary: %d transferred, %d skipped, %d failed, %s in %s (%s/s)\n",
+		m.transferred, m.skipped, m.failed, formatBytes(m.wire), elapsed.Round(100*time.Millisecond), formatBytes(rate))
+}
+
+// rateETA returns the transfer rate in bytes per second and the seconds
+// left until bytes reaches size, or -1 if that cannot be told yet
+func rateETA(bytes, size, transferred int64, elapsed time.Duration) (rate, eta int64) {
+	if elapsed > 0 {
+		rate = int64(float64(transferred) / elapsed.Seconds())
+	}
+	eta = -1
+	if size >= 0 && rate > 0 {
+		eta = max(size-bytes, 0) / rate
+	}
+	return rate, eta
+}
+
+// progressBar renders "[=====>    ]  45%", or a bar without a percentage
+// while the size is unknown
+func progressBar(bytes, size int64) string {
+	if size < 0 {
+		return "[" + strings.Repeat("?", progressBarWidth) + "]     "
+	}
+	frac := 1.0
+	if size > 0 {
+		frac = min(float64(bytes)/float64(size), 1)
+	}
+	filled := int(frac * progressBarWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	return fmt.Sprintf("[%s] %3d%%", bar, int(frac*100))
+}
+
+// progressStats renders "12.3 MiB/27.0 MiB  3.1 MiB/s  ETA 0:05"
+func progressStats(bytes, size, transferred int64, elapsed time.Duration) string {
+	rate, eta := rateETA(bytes, size, transferred, elapsed)
+	total, left := "?", "--:--"
+	if size >= 0 {
+		total = formatBytes(size)
+	}
+	if eta >= 0 {
+		left = formatDuration(time.Duration(eta) * time.Second)
+	}
+	return fmt.Sprintf("%s/%s  %s/s  ETA %s", formatBytes(bytes), total, formatBytes(rate), left)
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "12.3 MiB"
+func formatBytes(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	v, i := float64(n)/1024, 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %ciB", v, units[i])
+}
+
+// formatDuration renders a duration as m:ss or h:mm:ss
+func formatDuration(d time.Duration) string {
+	secs := int64(d.Round(time.Second).Seconds())
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// searchRecord is an entry found by search
+type searchRecord struct {
+	Type  string `json:"type"`
+	Path  string `json:"path"`
+	IsDir bool   `json:"isDir"`
+}
+
+// parseSearchArgs separates the flags of search (--type, --case-sensitive)
+// from the remote path and the query
+func parseSearchArgs(args []string) (filebrowser.SearchOptions, []string, error) {
+	var opts filebrowser.SearchOptions
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch {
+		case args[i] == "--case-sensitive":
+			opts.CaseSensitive = true
+			continue
+		case name == "--type":
+		default:
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		opts.Type = strings.ToLower(value)
+		if !slices.Contains(filebrowser.SearchTypes, opts.Type) {
+			return opts, nil, fmt.Errorf("unsupported type %q (want %s)", value, strings.Join(filebrowser.SearchTypes, ", "))
+		}
+	}
+	return opts, rest, nil
+}
+
+// searchPaths prints the remote paths the server finds below remotePath for
+// query, one per line as they arrive. Entries matching ignoreRegex, or inside
+// a directory that does, are left out.
+func searchPaths(ctx context.Context, client *filebrowser.Client, remotePath, query string, ignoreRegex *regexp.Regexp, opts filebrowser.SearchOptions) error {
+	base := path.Join("/", remotePath)
+	return client.Search(ctx, remotePath, query, opts, func(res filebrowser.SearchResult) error {
+		if ignoreRegex != nil {
+			rel := strings.TrimPrefix(strings.TrimPrefix(res.Path, base), "/")
+			for _, name := range strings.Split(rel, "/") {
+				if shouldIgnoreRegex(name, ignoreRegex) {
+					return nil
+				}
+			}
+		}
+		if jsonOut != nil {
+			jsonOut.record(searchRecord{Type: "match", Path: res.Path, IsDir: res.IsDir})
+		} else {
+			fmt.Println(res.Path)
+		}
+		return nil
+	})
+}
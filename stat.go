@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// statRecord is the metadata of a remote file or directory
+type statRecord struct {
+	Type string `json:"type"`
+	entryRecord
+	MIMEType  string            `json:"mimeType"`
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+// parseStatArgs separates the checksum algorithms asked for with -c or
+// --checksum (repeated, comma separated or "all") from the remote paths
+func parseStatArgs(args []string) ([]string, []string, error) {
+	var algorithms, paths []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "-c" && name != "--checksum" {
+			paths = append(paths, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		for _, algo := range strings.Split(strings.ToLower(value), ",") {
+			switch {
+			case algo == "all":
+				algorithms = append(algorithms, filebrowser.ChecksumAlgorithms...)
+			case slices.Contains(filebrowser.ChecksumAlgorithms, algo):
+				algorithms = append(algorithms, algo)
+			default:
+				return nil, nil, fmt.Errorf("unsupported checksum algorithm %q (want %s or all)", algo, strings.Join(filebrowser.ChecksumAlgorithms, ", "))
+			}
+		}
+	}
+	// Keep the order of ChecksumAlgorithms, once each
+	slices.SortFunc(algorithms, func(a, b string) int {
+		return slices.Index(filebrowser.ChecksumAlgorithms, a) - slices.Index(filebrowser.ChecksumAlgorithms, b)
+	})
+	return slices.Compact(algorithms), paths, nil
+}
+
+// statPaths prints the metadata of remote files and directories, with the
+// checksums of files computed by the server for each of algorithms
+func statPaths(ctx context.Context, client *filebrowser.Client, algorithms, paths []string) error {
+	for i, p := range paths {
+		item, err := client.Stat(ctx, p, algorithms...)
+		if err != nil {
+			return err
+		}
+		if jsonOut != nil {
+			jsonOut.record(statRecord{
+				Type:        "stat",
+				entryRecord: newEntryRecord(path.Dir(p), *item),
+				MIMEType:    item.MIMEType(),
+				Checksums:   item.Checksums,
+			})
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		printStat(item)
+	}
+	return nil
+}
+
+func printStat(item *filebrowser.RemoteItem) {
+	kind := "file"
+	if item.IsDir {
+		kind = "directory"
+	} else if item.IsSymlink {
+		kind = "symlink"
+	}
+	row := func(label, value string) {
+		if value != "" {
+			fmt.Printf("%-10s %s\n", label+":", value)
+		}
+	}
+	row("Path", item.Path)
+	row("Type", kind)
+	row("Size", fmt.Sprintf("%d (%s)", item.Size, formatBytes(item.Size)))
+	row("Modified", formatModified(item.Modified))
+	if item.Mode != 0 {
+		row("Mode", item.Mode.String())
+	}
+	row("Extension", item.Extension)
+	row("MIME type", item.MIMEType())
+	for _, algo := range filebrowser.ChecksumAlgorithms {
+		row(strings.ToUpper(algo), item.Checksums[algo])
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// defaultSumAlgorithm is the checksum sum computes unless -a says otherwise
+const defaultSumAlgorithm = "sha256"
+
+// sumOptions are the flags of sum
+type sumOptions struct {
+	algorithm string // empty until -a is given
+	manifest  string // checksum file given with -c
+	quiet     bool
+}
+
+// sumRecord is the checksum of a remote file or, with -c, the outcome of
+// checking one
+type sumRecord struct {
+	Type      string `json:"type"`
+	Path      string `json:"path"`
+	Algorithm string `json:"algorithm"`
+	Checksum  string `json:"checksum,omitempty"`
+	Expected  string `json:"expected,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// manifestEntry is a line of a checksum file
+type manifestEntry struct {
+	algorithm string
+	checksum  string
+	path      string
+}
+
+// parseSumArgs separates the flags of sum (-a, -c, --quiet) from the remote
+// paths
+func parseSumArgs(args []string) (sumOptions, []string, error) {
+	var opts sumOptions
+	var paths []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "-a", "--algorithm", "-c", "--check":
+		case "-q", "--quiet":
+			opts.quiet = true
+			continue
+		default:
+			paths = append(paths, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "-c" || name == "--check" {
+			opts.manifest = value
+			continue
+		}
+		opts.algorithm = strings.ToLower(value)
+		if !slices.Contains(filebrowser.ChecksumAlgorithms, opts.algorithm) {
+			return opts, nil, fmt.Errorf("unsupported checksum algorithm %q (want %s)", value, strings.Join(filebrowser.ChecksumAlgorithms, ", "))
+		}
+	}
+	return opts, paths, nil
+}
+
+// sumPaths prints the checksums of remote files in the format of sha256sum,
+// computed by the server; directories are walked in name order
+func sumPaths(ctx context.Context, client *filebrowser.Client, opts sumOptions, paths []string) error {
+	algo := opts.algorithm
+	if algo == "" {
+		algo = defaultSumAlgorithm
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer func() {
+		_ = out.Flush()
+	}()
+	emit := func(p string) error {
+		sum, err := client.Checksum(ctx, p, algo)
+		if err != nil {
+			return err
+		}
+		if jsonOut != nil {
+			jsonOut.record(sumRecord{Type: "checksum", Path: p, Algorithm: algo, Checksum: sum})
+		} else {
+			fmt.Fprintf(out, "%s  %s\n", sum, p)
+		}
+		return nil
+	}
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		items, err := client.List(ctx, dir)
+		if err != nil {
+			return err
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+		for _, item := range items {
+			p := path.Join(dir, item.Name)
+			if item.IsDir {
+				err = walk(p)
+			} else {
+				err = emit(p)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, p := range paths {
+		isDir, err := client.IsDir(ctx, p)
+		if err == nil && isDir {
+			err = walk(p)
+		} else if err == nil {
+			err = emit(p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// checkManifest checks the remote files listed in a checksum file against
+// their checksums, like sha256sum -c. Relative paths in it are taken from
+// remoteDir. Every file is checked; the error counts those that failed.
+func checkManifest(ctx context.Context, client *filebrowser.Client, opts sumOptions, remoteDir string) error {
+	entries, err := readManifest(opts.manifest, opts.algorithm)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no checksums found in %s", opts.manifest)
+	}
+	failed := 0
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rec := sumRecord{Type: "checksum", Path: path.Join("/", remoteDir, e.path), Algorithm: e.algorithm, Expected: e.checksum}
+		sum, err := client.Checksum(ctx, rec.Path, e.algorithm)
+		switch {
+		case errors.Is(err, filebrowser.ErrNotFound):
+			rec.Status = "missing"
+		case err != nil:
+			rec.Status, rec.Error = "error", err.Error()
+		case strings.EqualFold(sum, e.checksum):
+			rec.Status, rec.Checksum = "ok", sum
+		default:
+			rec.Status, rec.Checksum = "failed", sum
+		}
+		if rec.Status != "ok" {
+			failed++
+		}
+		switch {
+		case jsonOut != nil:
+			jsonOut.record(rec)
+		case rec.Status == "ok":
+			if !opts.quiet {
+				fmt.Printf("%s: OK\n", e.path)
+			}
+		case rec.Status == "failed":
+			fmt.Printf("%s: FAILED\n", e.path)
+		case rec.Status == "missing":
+			fmt.Printf("%s: MISSING\n", e.path)
+		default:
+			fmt.Printf("%s: FAILED (%s)\n", e.path, rec.Error)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed the check", failed, len(entries))
+	}
+	return nil
+}
+
+// readManifest reads a checksum file ("-" for standard input) as written by
+// sha256sum and friends, or in their --tag format. Unless algo is set, the
+// algorithm of each line follows from its tag or the length of its checksum.
+func readManifest(name, algo string) ([]manifestEntry, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open checksum file: %w", err)
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		r = f
+	}
+	var entries []manifestEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e, ok := parseManifestLine(line, algo)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: not a checksum line", name, n)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checksum file: %w", err)
+	}
+	return entries, nil
+}
+
+// parseManifestLine parses "<checksum>  <path>", "<checksum> *<path>" or
+// "<ALGO> (<path>) = <checksum>"; a leading backslash marks an escaped path
+func parseManifestLine(line, algo string) (manifestEntry, bool) {
+	escaped := strings.HasPrefix(line, "\\")
+	line = strings.TrimPrefix(line, "\\")
+	var e manifestEntry
+	if tag, rest, ok := strings.Cut(line, " ("); ok && !strings.Contains(tag, " ") {
+		i := strings.LastIndex(rest, ") = ")
+		if i < 0 {
+			return e, false
+		}
+		e = manifestEntry{algorithm: strings.ToLower(tag), path: rest[:i], checksum: rest[i+4:]}
+	} else {
+		sum, p, ok := strings.Cut(line, " ")
+		if !ok || len(p) < 2 || (p[0] != ' ' && p[0] != '*') {
+			return e, false
+		}
+		e = manifestEntry{checksum: sum, path: p[1:]}
+		switch len(sum) {
+		case 32:
+			e.algorithm = "md5"
+		case 40:
+			e.algorithm = "sha1"
+		case 64:
+			e.algorithm = "sha256"
+		case 128:
+			e.algorithm = "sha512"
+		}
+	}
+	if algo != "" {
+		e.algorithm = algo
+	}
+	if escaped {
+		e.path = strings.NewReplacer("\\\\", "\\", "\\n", "\n", "\\r", "\r").Replace(e.path)
+	}
+	if !slices.Contains(filebrowser.ChecksumAlgorithms, e.algorithm) || e.path == "" || !isHex(e.checksum) {
+		return e, false
+	}
+	return e, true
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+#!/usr/bin/env bash
+# Test script for cat/head/tail commands
+# Tests whole files, byte ranges, line limits and multiple files
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "cat/head/tail commands"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-cat-$TEST_ID"
+LOCAL_FILE="cat-$TEST_ID.log"
+LOCAL_FILE2="cat2-$TEST_ID.txt"
+
+step "Setting up test environment"
+create_test_file "$LOCAL_FILE" "$(seq -f 'line %g' 1 30)"
+create_test_file "$LOCAL_FILE2" "second file"
+assert "Upload test file" ./fbcli upload "$LOCAL_FILE" "$REMOTE_DIR"
+assert "Upload second test file" ./fbcli upload "$LOCAL_FILE2" "$REMOTE_DIR"
+track_remote "$REMOTE_DIR"
+REMOTE_FILE="$REMOTE_DIR/$LOCAL_FILE"
+REMOTE_FILE2="$REMOTE_DIR/$LOCAL_FILE2"
+
+step "Testing cat"
+assert_contains "cat prints the first line" "^line 1$" ./fbcli cat "$REMOTE_FILE"
+assert_contains "cat prints the last line" "^line 30$" ./fbcli cat "$REMOTE_FILE"
+assert "cat output matches the local file" cmp -s "$LOCAL_FILE" <(./fbcli cat "$REMOTE_FILE")
+assert_contains "cat prints several files" "second file" ./fbcli cat "$REMOTE_FILE" "$REMOTE_FILE2"
+
+step "Testing cat byte ranges"
+# "line 1\n" is 7 bytes long
+assert_contains "cat --offset skips bytes" "^line 2$" ./fbcli cat --offset 7 --length 7 "$REMOTE_FILE"
+assert_not_contains "cat --length stops early" "line 3" ./fbcli cat --offset 7 --length 7 "$REMOTE_FILE"
+assert_contains "cat with a negative offset reads the end" "^line 30$" ./fbcli cat --offset -8 "$REMOTE_FILE"
+assert_not_contains "cat with a negative offset skips the start" "line 29" ./fbcli cat --offset=-8 "$REMOTE_FILE"
+
+step "Testing head"
+assert_contains "head prints the first lines" "^line 10$" ./fbcli head "$REMOTE_FILE"
+assert_not_contains "head stops after 10 lines" "line 11" ./fbcli head "$REMOTE_FILE"
+assert_contains "head -n prints that many lines" "^line 3$" ./fbcli head -n 3 "$REMOTE_FILE"
+assert_not_contains "head -n stops after that many lines" "line 4" ./fbcli head -n 3 "$REMOTE_FILE"
+
+step "Testing tail"
+assert_contains "tail prints the last lines" "^line 21$" ./fbcli tail "$REMOTE_FILE"
+assert_not_contains "tail starts 10 lines before the end" "line 20" ./fbcli tail "$REMOTE_FILE"
+assert_contains "tail -n prints that many lines" "^line 28$" ./fbcli tail -n 3 "$REMOTE_FILE"
+assert_not_contains "tail -n starts that many lines before the end" "line 27" ./fbcli tail -n 3 "$REMOTE_FILE"
+assert_contains "tail names each of several files" "==> $REMOTE_FILE2 <==" ./fbcli tail -n 1 "$REMOTE_FILE" "$REMOTE_FILE2"
+
+step "Testing error handling"
+assert_fails "cat fails on non-existent file" ./fbcli cat "$REMOTE_DIR/non-existent.txt"
+assert_fails "cat fails on a directory" ./fbcli cat "$REMOTE_DIR"
+assert_fails "head fails without a path" ./fbcli head -n 3
+
+finish_test
@@ -0,0 +1,64 @@
+#!/usr/bin/env bash
+# Test script for the config file and profiles
+# Tests profile selection and the precedence of flags, profiles, environment and config
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "config file and profiles"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+CONFIG_DIR="config-$TEST_ID"
+CONFIG="$CONFIG_DIR/config.toml"
+
+step "Setting up test environment"
+mkdir -p "$CONFIG_DIR"
+track_local "$CONFIG_DIR"
+cat > "$CONFIG" <<TOML
+default_profile = "main"
+timeout = "5s"
+
+[profiles.main]
+url = "$FILEBROWSER_URL"
+username = "$FILEBROWSER_USERNAME"
+
+[profiles.lab]
+url = "http://127.0.0.1:1"
+username = "labuser"
+timeout = "7s"
+
+[profiles.cmd]
+url = "$FILEBROWSER_URL"
+username = "$FILEBROWSER_USERNAME"
+password_command = "echo profile-password"
+TOML
+# Without the environment, settings come from the config file
+NOENV=(env -u FILEBROWSER_URL -u FILEBROWSER_USERNAME -u FBCLI_PROFILE -u FBCLI_CONFIG)
+
+step "Testing profile selection"
+assert_contains "default_profile selects a profile" "^Profile: main \[default_profile\]" "${NOENV[@]}" ./fbcli --config "$CONFIG" show
+assert_contains "Profile settings are used" "^Username: $FILEBROWSER_USERNAME \[profile main\]" "${NOENV[@]}" ./fbcli --config "$CONFIG" show
+assert_contains "Top-level settings are defaults" "^Timeout: 5s \[config file\]" "${NOENV[@]}" ./fbcli --config "$CONFIG" show
+assert_contains "--profile selects another profile" "^Username: labuser \[profile lab\]" "${NOENV[@]}" ./fbcli --config "$CONFIG" --profile lab show
+assert_contains "FBCLI_PROFILE selects a profile" "^Profile: lab \[env FBCLI_PROFILE\]" "${NOENV[@]}" FBCLI_PROFILE=lab ./fbcli --config "$CONFIG" show
+assert_contains "FBCLI_CONFIG names the config file" "^Config: $CONFIG$" "${NOENV[@]}" FBCLI_CONFIG="$CONFIG" ./fbcli show
+assert "The default profile logs in" "${NOENV[@]}" ./fbcli --config "$CONFIG" ls /
+
+step "Testing precedence"
+assert_contains "Profile settings beat top-level ones" "^Timeout: 7s \[profile lab\]" "${NOENV[@]}" ./fbcli --config "$CONFIG" --profile lab show
+assert_contains "Flags beat profile settings" "^Timeout: 9s \[--timeout\]" "${NOENV[@]}" ./fbcli --config "$CONFIG" --profile lab --timeout 9s show
+assert_contains "--profile beats the environment" "^URL: http://127.0.0.1:1 \[profile lab\]" "${NOENV[@]}" FILEBROWSER_URL=http://env.example ./fbcli --config "$CONFIG" --profile lab show
+assert_contains "FBCLI_PROFILE beats the environment" "^URL: http://127.0.0.1:1 \[profile lab\]" "${NOENV[@]}" FBCLI_PROFILE=lab FILEBROWSER_URL=http://env.example ./fbcli --config "$CONFIG" show
+assert_contains "The environment beats default_profile" "^URL: http://env.example \[env FILEBROWSER_URL\]" "${NOENV[@]}" FILEBROWSER_URL=http://env.example ./fbcli --config "$CONFIG" show
+assert_not_contains "FILEBROWSER_PASSWORD is not sent to a profile's server" "env FILEBROWSER_PASSWORD" "${NOENV[@]}" FILEBROWSER_PASSWORD=env-password ./fbcli --config "$CONFIG" --profile lab show
+assert_contains "FILEBROWSER_PASSWORD goes with FILEBROWSER_URL" "^Password: .* \[env FILEBROWSER_PASSWORD\]" "${NOENV[@]}" FILEBROWSER_URL=http://env.example FILEBROWSER_PASSWORD=env-password ./fbcli --config "$CONFIG" show
+assert_contains "password_command beats FILEBROWSER_PASSWORD" "^Password command: echo profile-password \[profile cmd\]" "${NOENV[@]}" FILEBROWSER_PASSWORD=env-password ./fbcli --config "$CONFIG" --profile cmd show
+assert_contains "--profile beats FBCLI_PROFILE" "^Profile: main \[--profile\]" "${NOENV[@]}" FBCLI_PROFILE=lab ./fbcli --config "$CONFIG" --profile main show
+
+step "Testing error handling"
+assert_fails "Unknown profile fails" "${NOENV[@]}" ./fbcli --config "$CONFIG" --profile nope ls /
+assert_fails "Missing config file fails" "${NOENV[@]}" ./fbcli --config "$CONFIG_DIR/missing.toml" show
+echo 'colour = "blue"' > "$CONFIG_DIR/bad.toml"
+assert_fails "Unknown setting fails" "${NOENV[@]}" ./fbcli --config "$CONFIG_DIR/bad.toml" show
+
+finish_test
@@ -0,0 +1,63 @@
+#!/usr/bin/env bash
+# Test script for cp command
+# Tests copying files and directories on the server and handling existing destinations
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "cp command"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-cp-$TEST_ID"
+LOCAL_FILE="cp-$TEST_ID.txt"
+LOCAL_FILE2="cp2-$TEST_ID.txt"
+
+step "Setting up test environment"
+create_test_file "$LOCAL_FILE" "original content"
+create_test_file "$LOCAL_FILE2" "second file"
+assert "Upload test file" ./fbcli upload "$LOCAL_FILE" "$REMOTE_DIR/src"
+assert "Upload second test file" ./fbcli upload "$LOCAL_FILE2" "$REMOTE_DIR/src"
+assert "Create destination directory" ./fbcli mkdir "$REMOTE_DIR/dst"
+track_remote "$REMOTE_DIR"
+SRC_FILE="$REMOTE_DIR/src/$LOCAL_FILE"
+SRC_FILE2="$REMOTE_DIR/src/$LOCAL_FILE2"
+
+step "Testing file copies"
+assert "Copy a file to a new name" ./fbcli cp "$SRC_FILE" "$REMOTE_DIR/copy.txt"
+assert_contains "Copy has the same content" "^original content$" ./fbcli cat "$REMOTE_DIR/copy.txt"
+assert_remote_exists "Original is still there" "$SRC_FILE"
+assert "Copy a file into a directory" ./fbcli cp "$SRC_FILE" "$REMOTE_DIR/dst"
+assert_remote_exists "File was copied into the directory" "$REMOTE_DIR/dst/$LOCAL_FILE"
+assert "Copy several files into a directory" ./fbcli copy -f "$SRC_FILE" "$SRC_FILE2" "$REMOTE_DIR/dst"
+assert_remote_exists "Second file was copied into the directory" "$REMOTE_DIR/dst/$LOCAL_FILE2"
+
+step "Testing names with special characters"
+SPECIAL="$REMOTE_DIR/a&b+c#d=e 50%.txt"
+assert "Copy to a name with & + # = and %" ./fbcli cp "$SRC_FILE" "$SPECIAL"
+assert_contains "Copy kept the name as given" "^a&b+c#d=e 50%.txt$" ./fbcli ls -s "$REMOTE_DIR"
+assert_contains "Copy under the special name has the content" "^original content$" ./fbcli cat "$SPECIAL"
+assert "Copy from a name with special characters" ./fbcli cp "$SPECIAL" "$REMOTE_DIR/dst"
+assert_remote_exists "File with special characters copied into the directory" "$REMOTE_DIR/dst/a&b+c#d=e 50%.txt"
+
+step "Testing existing destinations"
+assert_fails "Copy onto an existing file fails" ./fbcli cp "$SRC_FILE2" "$REMOTE_DIR/copy.txt"
+assert_contains "Failed copy left the file alone" "^original content$" ./fbcli cat "$REMOTE_DIR/copy.txt"
+assert "Copy -n skips an existing file" ./fbcli cp -n "$SRC_FILE2" "$REMOTE_DIR/copy.txt"
+assert_contains "Skipped copy left the file alone" "^original content$" ./fbcli cat "$REMOTE_DIR/copy.txt"
+assert "Copy -f replaces an existing file" ./fbcli cp -f "$SRC_FILE2" "$REMOTE_DIR/copy.txt"
+assert_contains "Replaced file has the new content" "^second file$" ./fbcli cat "$REMOTE_DIR/copy.txt"
+assert "Copy --auto-rename keeps both" ./fbcli cp --auto-rename "$SRC_FILE" "$REMOTE_DIR/copy.txt"
+assert_contains "Server picked a free name" "copy(1).txt" ./fbcli ls -s "$REMOTE_DIR"
+
+step "Testing directory copies"
+assert_fails "Copy of a directory needs -r" ./fbcli cp "$REMOTE_DIR/src" "$REMOTE_DIR/src-copy"
+assert "Copy -r copies a directory" ./fbcli cp -r "$REMOTE_DIR/src" "$REMOTE_DIR/src-copy"
+assert_remote_exists "Directory contents were copied" "$REMOTE_DIR/src-copy/$LOCAL_FILE2"
+
+step "Testing error handling"
+assert_fails "Copy fails on non-existent source" ./fbcli cp "$REMOTE_DIR/non-existent.txt" "$REMOTE_DIR/x.txt"
+assert_fails "Several sources need a directory" ./fbcli cp "$SRC_FILE" "$SRC_FILE2" "$REMOTE_DIR/copy.txt"
+assert_fails "Copy of a directory into itself fails" ./fbcli cp -r "$REMOTE_DIR/src" "$REMOTE_DIR/src/inner"
+assert_fails "Copy fails without a destination" ./fbcli cp "$SRC_FILE"
+
+finish_test
@@ -29,6 +29,19 @@ assert "Download single file" ./fbcli download "$REMOTE_DIR/$LOCAL_SETUP_DIR/dow
 assert_exists "File downloaded successfully" "$DOWNLOAD_FILE"
 track_local "$DOWNLOAD_FILE"
 
+step "Testing resumed download"
+BIG_FILE="big-download-$TEST_ID.bin"
+head -c 2000000 /dev/urandom > "$LOCAL_SETUP_DIR/$BIG_FILE"
+assert "Upload large file" ./fbcli upload "$LOCAL_SETUP_DIR/$BIG_FILE" "$REMOTE_DIR/$LOCAL_SETUP_DIR"
+RESUMED_FILE="resumed-$TEST_ID.bin"
+track_local "$RESUMED_FILE"
+# An interrupted download leaves the first part behind
+head -c 700000 "$LOCAL_SETUP_DIR/$BIG_FILE" > "$RESUMED_FILE.part"
+track_local "$RESUMED_FILE.part"
+assert "Download continues the partial file" ./fbcli download "$REMOTE_DIR/$LOCAL_SETUP_DIR/$BIG_FILE" "$RESUMED_FILE"
+assert "Resumed download matches the original" cmp "$LOCAL_SETUP_DIR/$BIG_FILE" "$RESUMED_FILE"
+assert_not_exists "Partial file is gone" "$RESUMED_FILE.part"
+
 step "Testing directory download"
 DOWNLOAD_DIR="downloaded-dir-$TEST_ID"
 assert "Download directory" ./fbcli download "$REMOTE_DIR/$LOCAL_SETUP_DIR" "$DOWNLOAD_DIR.zip"
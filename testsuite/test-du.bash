@@ -0,0 +1,48 @@
+#!/usr/bin/env bash
+# Test script for du command
+# Tests recursive sizes, depth limits, summaries and the disk quota
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "du command"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-du-$TEST_ID"
+LOCAL_DIR="du-$TEST_ID"
+
+step "Setting up test environment"
+mkdir -p "$LOCAL_DIR/sub/deep"
+head -c 1000 /dev/zero > "$LOCAL_DIR/top.bin"
+head -c 2000 /dev/zero > "$LOCAL_DIR/sub/middle.bin"
+head -c 3000 /dev/zero > "$LOCAL_DIR/sub/deep/bottom.bin"
+track_local "$LOCAL_DIR"
+assert "Upload test tree" ./fbcli upload "$LOCAL_DIR" "$REMOTE_DIR"
+track_remote "$REMOTE_DIR"
+ROOT="$REMOTE_DIR/$LOCAL_DIR"
+
+step "Testing du"
+assert_contains "du adds up the whole tree" "^6000 *$ROOT$" ./fbcli du "$ROOT"
+assert_contains "du shows subdirectories" "^5000 *$ROOT/sub$" ./fbcli du "$ROOT"
+assert_contains "du shows nested directories" "^3000 *$ROOT/sub/deep$" ./fbcli du "$ROOT"
+assert_contains "du lists the largest first" "^6000 " bash -c "./fbcli du '$ROOT' | head -1"
+assert_not_contains "du hides files by default" "top.bin" ./fbcli du "$ROOT"
+assert_contains "du -a shows files" "^1000 *$ROOT/top.bin$" ./fbcli du -a "$ROOT"
+
+step "Testing depth and summaries"
+assert_not_contains "du -d 1 stops at the first level" "deep" ./fbcli du -d 1 "$ROOT"
+assert_contains "du -d 1 shows the first level" "$ROOT/sub$" ./fbcli du -d 1 "$ROOT"
+assert_not_contains "du -s only shows the total" "$ROOT/sub" ./fbcli du -s "$ROOT"
+assert_contains "du -h shows human-readable sizes" "^5.9 KiB *$ROOT$" ./fbcli du -s -h "$ROOT"
+assert_contains "du -i leaves out matching entries" "^1000 *$ROOT$" ./fbcli du -s -i "^sub$" "$ROOT"
+
+step "Testing quota and JSON output"
+assert_contains "du --quota shows the disk usage" "^Disk usage: .* used" ./fbcli du -s --quota "$ROOT"
+assert_contains "du prints JSON records" "\"type\":\"usage\",\"path\":\"$ROOT\",\"isDir\":true,\"size\":6000,\"files\":3,\"dirs\":2" ./fbcli --output json du -s "$ROOT"
+
+step "Testing error handling"
+assert_fails "du fails on non-existent path" ./fbcli du "$REMOTE_DIR/non-existent"
+assert_fails "du rejects an invalid depth" ./fbcli du -d x "$ROOT"
+assert_fails "du --quota rejects several paths" ./fbcli du --quota "$ROOT" "$ROOT/sub"
+
+finish_test
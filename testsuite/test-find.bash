@@ -0,0 +1,65 @@
+#!/usr/bin/env bash
+# Test script for find command
+# Tests name, type, size, time and depth tests and the -print0, -exec and
+# -delete actions
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "find command"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-find-$TEST_ID"
+LOCAL_DIR="find-$TEST_ID"
+
+step "Setting up test environment"
+mkdir -p "$LOCAL_DIR/logs/old" "$LOCAL_DIR/keep"
+echo "first" > "$LOCAL_DIR/logs/app.log"
+head -c 3000 /dev/zero > "$LOCAL_DIR/logs/old/big.log"
+echo "notes" > "$LOCAL_DIR/keep/notes.txt"
+echo "extra" > "$LOCAL_DIR/keep/extra.txt"
+track_local "$LOCAL_DIR"
+assert "Upload test tree" ./fbcli upload "$LOCAL_DIR" "$REMOTE_DIR"
+track_remote "$REMOTE_DIR"
+ROOT="$REMOTE_DIR/$LOCAL_DIR"
+
+step "Testing tests"
+assert_contains "find lists the starting path" "^$ROOT$" ./fbcli find "$ROOT"
+assert_contains "find lists nested files" "^$ROOT/logs/old/big.log$" ./fbcli find "$ROOT"
+assert_contains "find -name matches base names" "^$ROOT/logs/app.log$" ./fbcli find "$ROOT" -name "*.log"
+assert_not_contains "find -name leaves out other names" "notes.txt" ./fbcli find "$ROOT" -name "*.log"
+assert_contains "find -iname ignores case" "app.log$" ./fbcli find "$ROOT" -iname "APP.*"
+assert_contains "find -path matches across directories" "big.log$" ./fbcli find "$ROOT" -path "*/logs/*.log"
+assert_contains "find -regex matches the whole path" "notes.txt$" ./fbcli find "$ROOT" -regex ".*/n[a-z]+\.txt"
+assert_not_contains "find -type f leaves out directories" "^$ROOT/logs$" ./fbcli find "$ROOT" -type f
+assert_contains "find -type d shows directories" "^$ROOT/logs/old$" ./fbcli find "$ROOT" -type d
+assert_contains "find -size +2K finds large files" "big.log$" ./fbcli find "$ROOT" -type f -size +2K
+assert_not_contains "find -size +2K leaves out small files" "app.log" ./fbcli find "$ROOT" -type f -size +2K
+assert_contains "find -mtime -1 finds new files" "app.log$" ./fbcli find "$ROOT" -type f -mtime -1
+assert_not_contains "find -mtime +30 leaves out new files" "log" ./fbcli find "$ROOT" -type f -mtime +30
+assert_not_contains "find -maxdepth 1 stays at the top" "app.log" ./fbcli find "$ROOT" -maxdepth 1
+assert_not_contains "find -mindepth 1 leaves out the start" "^$ROOT$" ./fbcli find "$ROOT" -mindepth 1
+
+step "Testing actions and JSON output"
+assert_contains "find -print0 ends paths with NUL" "app.log$" bash -c "./fbcli find '$ROOT' -name app.log -print0 | tr '\\0' '\\n'"
+assert_contains "find -exec runs a command per match" "^found $ROOT/logs/app.log$" ./fbcli find "$ROOT" -name app.log -exec echo found {} ";"
+assert_contains "find -exec keeps flags of the command" "^--output $ROOT/logs/app.log -j$" ./fbcli find "$ROOT" -name app.log -exec echo --output {} -j ";"
+assert_contains "find -exec + runs a command once" "^all .*app.log .*big.log$" ./fbcli find "$ROOT" -name "*.log" -exec echo all {} +
+assert_contains "find prints JSON records" "\"type\":\"match\",\"name\":\"big.log\"" ./fbcli --output json find "$ROOT" -name big.log
+assert_contains "find -delete --dry-run shows the deletions" "Would delete $ROOT/logs/old/big.log" ./fbcli find "$ROOT" -name "*.log" -delete --dry-run
+assert_remote_exists "Dry run keeps the files" "$ROOT/logs/old/big.log"
+assert "find -delete removes the matches" ./fbcli find "$ROOT" -name "*.log" -delete
+assert_not_contains "Deleted files are gone" "log$" ./fbcli find "$ROOT"
+assert_contains "find -delete keeps directories with other files" "not deleting .*keep: directory not empty" ./fbcli find "$ROOT" -name keep -delete
+assert_remote_exists "Non-empty directory is kept" "$ROOT/keep/notes.txt"
+assert "find -delete with -i removes the visible entries" ./fbcli find "$ROOT/keep" -i "^notes" -name "*" -delete
+assert_remote_exists "find -delete keeps ignored files" "$ROOT/keep/notes.txt"
+assert_not_contains "find -delete removed the other files" "extra.txt" ./fbcli find "$ROOT"
+
+step "Testing error handling"
+assert_fails "find fails on non-existent path" ./fbcli find "$REMOTE_DIR/non-existent"
+assert_fails "find rejects unknown options" ./fbcli find "$ROOT" -bogus x
+assert_fails "find rejects an invalid type" ./fbcli find "$ROOT" -type x
+assert_fails "find rejects an unterminated -exec" ./fbcli find "$ROOT" -exec echo {}
+
+finish_test
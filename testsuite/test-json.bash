@@ -0,0 +1,64 @@
+#!/usr/bin/env bash
+# Test script for --output json
+# Tests the JSON records of ls, rm and the sync commands
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "JSON output"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-json-$TEST_ID"
+LOCAL_DIR="json-$TEST_ID"
+SYNC_DIR="json-sync-$TEST_ID"
+
+step "Setting up test environment"
+create_test_dir "$LOCAL_DIR" 2
+track_local "$LOCAL_DIR"
+assert "Upload test files" ./fbcli upload "$LOCAL_DIR" "$REMOTE_DIR"
+track_remote "$REMOTE_DIR"
+
+step "Testing ls"
+assert_contains "ls prints a JSON array" "^\[{\"name\":.*}\]$" ./fbcli --output json ls "$REMOTE_DIR/$LOCAL_DIR"
+assert_contains "ls records hold the full path" "\"name\":\"file1.txt\",\"path\":\"$REMOTE_DIR/$LOCAL_DIR/file1.txt\",\"isDir\":false" ./fbcli --output json ls "$REMOTE_DIR/$LOCAL_DIR"
+assert_contains "ls records mark directories" "\"name\":\"$LOCAL_DIR\",\"path\":\"$REMOTE_DIR/$LOCAL_DIR\",\"isDir\":true" ./fbcli --output json ls "$REMOTE_DIR"
+assert_contains "FBCLI_OUTPUT selects JSON" "^\[{\"name\":" env FBCLI_OUTPUT=json ./fbcli ls "$REMOTE_DIR"
+assert_contains "ls of an empty directory prints an empty array" "^\[\]$" sh -c "./fbcli mkdir '$REMOTE_DIR/empty' >/dev/null && ./fbcli --output json ls '$REMOTE_DIR/empty'"
+
+step "Testing syncto"
+create_test_file "$LOCAL_DIR/file3.txt" "third file"
+SYNCTO_OUT=$(./fbcli --output json syncto "$LOCAL_DIR" "$REMOTE_DIR/$LOCAL_DIR" 2>/dev/null)
+assert_contains "syncto starts with a plan record" "^{\"type\":\"plan\",\"files\":3," echo "$SYNCTO_OUT"
+assert_contains "syncto records the new file" "^{\"type\":\"file\",\"status\":\"done\",\"path\":\"$REMOTE_DIR/$LOCAL_DIR/file3.txt\",\"size\":11," echo "$SYNCTO_OUT"
+assert_contains "syncto records the skipped files" "^{\"type\":\"file\",\"status\":\"skipped\",\"path\":\"$REMOTE_DIR/$LOCAL_DIR/file1.txt\"" echo "$SYNCTO_OUT"
+assert_contains "syncto ends with a summary record" "^{\"type\":\"summary\",\"transferred\":1,\"skipped\":2,\"failed\":0," echo "$SYNCTO_OUT"
+assert_not_contains "syncto prints only JSON on stdout" "^[^{]" echo "$SYNCTO_OUT"
+
+step "Testing syncto dry run"
+rm -f "$LOCAL_DIR/file2.txt"
+assert_contains "Dry run records the planned deletion" "^{\"type\":\"change\",\"action\":\"delete\",\"path\":\"$REMOTE_DIR/$LOCAL_DIR/file2.txt\",\"local\":false,\"reason\":\"not in source\",\"dryRun\":true}$" ./fbcli --output json syncto --dry-run "$LOCAL_DIR" "$REMOTE_DIR/$LOCAL_DIR"
+assert_remote_exists "Dry run keeps the remote file" "$REMOTE_DIR/$LOCAL_DIR/file2.txt"
+assert_contains "syncto records the deletion" "^{\"type\":\"change\",\"action\":\"delete\",\"path\":\"$REMOTE_DIR/$LOCAL_DIR/file2.txt\",\"local\":false,\"reason\":\"not in source\",\"dryRun\":false}$" ./fbcli --output json syncto "$LOCAL_DIR" "$REMOTE_DIR/$LOCAL_DIR"
+assert_remote_not_exists "Remote file deleted" "$REMOTE_DIR/$LOCAL_DIR/file2.txt"
+
+step "Testing syncfrom"
+SYNCFROM_OUT=$(./fbcli --output json syncfrom "$REMOTE_DIR/$LOCAL_DIR" "$SYNC_DIR" 2>/dev/null)
+track_local "$SYNC_DIR"
+assert_contains "syncfrom records the local directory it creates" "^{\"type\":\"change\",\"action\":\"mkdir\",\"path\":\"$SYNC_DIR\",\"local\":true," echo "$SYNCFROM_OUT"
+assert_contains "syncfrom plans the files to look at" "^{\"type\":\"plan\",\"files\":2," echo "$SYNCFROM_OUT"
+assert_contains "syncfrom records the downloaded file" "^{\"type\":\"file\",\"status\":\"done\",\"path\":\"$REMOTE_DIR/$LOCAL_DIR/file3.txt\",\"size\":11," echo "$SYNCFROM_OUT"
+assert_contains "syncfrom ends with a summary record" "^{\"type\":\"summary\",\"transferred\":2,\"skipped\":0,\"failed\":0," echo "$SYNCFROM_OUT"
+assert_not_contains "syncfrom prints only JSON on stdout" "^[^{]" echo "$SYNCFROM_OUT"
+
+step "Testing rm"
+assert_contains "rm records the deletion" "^{\"type\":\"change\",\"action\":\"delete\",\"path\":\"$REMOTE_DIR/$LOCAL_DIR/file1.txt\",\"local\":false,\"dryRun\":false}$" ./fbcli --output json rm "$REMOTE_DIR/$LOCAL_DIR/file1.txt"
+assert_remote_not_exists "Remote file deleted" "$REMOTE_DIR/$LOCAL_DIR/file1.txt"
+assert_contains "rm --dry-run records the planned deletion" "\"action\":\"delete\",\"path\":\"$REMOTE_DIR/$LOCAL_DIR/file3.txt\",\"local\":false,\"dryRun\":true}$" ./fbcli --output json rm --dry-run "$REMOTE_DIR/$LOCAL_DIR/file3.txt"
+assert_remote_exists "Dry run keeps the remote file" "$REMOTE_DIR/$LOCAL_DIR/file3.txt"
+
+step "Testing errors"
+assert_fails "rm of a missing path fails" ./fbcli --output json rm "$REMOTE_DIR/missing.txt"
+assert_contains "Errors go to stderr as a JSON record" "^{\"type\":\"error\",\"message\":\".*\",\"exitCode\":1" sh -c "./fbcli --output json rm '$REMOTE_DIR/missing.txt' 2>&1 >/dev/null; true"
+assert_contains "Errors leave stdout empty" "^$" sh -c "./fbcli --output json rm '$REMOTE_DIR/missing.txt' 2>/dev/null; true"
+
+finish_test
@@ -0,0 +1,46 @@
+#!/usr/bin/env bash
+# Test script for the login token cache and logout
+# Tests that tokens are cached per profile, reused and removed by logout
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "token cache and logout"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+CACHE_DIR="$PWD/cache-$TEST_ID"
+TOKEN_FILE="$CACHE_DIR/fbcli/tokens/default.json"
+
+step "Setting up test environment"
+mkdir -p "$CACHE_DIR"
+track_local "$CACHE_DIR"
+# A cache of its own keeps the tokens of other tests out of the way
+SAVED_CACHE_HOME="${XDG_CACHE_HOME-}"
+export XDG_CACHE_HOME="$CACHE_DIR"
+
+step "Testing the token cache"
+assert "Login caches the token" ./fbcli ls /
+assert_exists "Token cache file created" "$TOKEN_FILE"
+assert_contains "Token cache is private" "^600$" stat -c %a "$TOKEN_FILE"
+assert "Cached token is used instead of the password" env FILEBROWSER_PASSWORD=wrong-password ./fbcli ls /
+
+step "Testing logout"
+assert_contains "logout removes the cached token" "Cached token removed." ./fbcli logout
+assert_not_exists "Token cache file removed" "$TOKEN_FILE"
+assert_fails "Without a cached token the password is needed" env FILEBROWSER_PASSWORD=wrong-password ./fbcli ls /
+assert "logout without a cached token succeeds" ./fbcli logout
+
+step "Testing a damaged cache"
+assert "Login caches a new token" ./fbcli ls /
+echo "not json" > "$TOKEN_FILE"
+assert "A damaged cache falls back to logging in" ./fbcli ls /
+assert_contains "The damaged cache is replaced" "token" cat "$TOKEN_FILE"
+
+# Cleanup logs in again, which must not recreate the removed cache
+if [ -n "$SAVED_CACHE_HOME" ]; then
+    export XDG_CACHE_HOME="$SAVED_CACHE_HOME"
+else
+    unset XDG_CACHE_HOME
+fi
+
+finish_test
@@ -0,0 +1,66 @@
+#!/usr/bin/env bash
+# Test script for password sources
+# Tests --password-file, password_command, ~/.netrc and their precedence
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "password sources"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+WORK_DIR="password-$TEST_ID"
+SERVER_HOST=$(echo "$FILEBROWSER_URL" | sed -E 's#^[a-z]+://([^/:]+).*#\1#')
+
+step "Setting up test environment"
+mkdir -p "$WORK_DIR"
+track_local "$WORK_DIR"
+# A cache of its own, emptied by logout, makes every command log in
+SAVED_CACHE_HOME="${XDG_CACHE_HOME-}"
+export XDG_CACHE_HOME="$PWD/$WORK_DIR/cache"
+echo "$FILEBROWSER_PASSWORD" > "$WORK_DIR/password"
+echo "wrong-password" > "$WORK_DIR/wrong"
+printf 'machine %s\n  login %s\n  password %s\n' "$SERVER_HOST" "$FILEBROWSER_USERNAME" "$FILEBROWSER_PASSWORD" > "$WORK_DIR/netrc"
+printf 'url = "%s"\nusername = "%s"\npassword_command = "cat %s"\n' "$FILEBROWSER_URL" "$FILEBROWSER_USERNAME" "$WORK_DIR/password" > "$WORK_DIR/config.toml"
+NOPASS=(env -u FILEBROWSER_PASSWORD -u FBCLI_PROFILE -u FBCLI_CONFIG NETRC="$WORK_DIR/no-netrc")
+
+step "Testing --password-file"
+assert "--password-file supplies the password" "${NOPASS[@]}" ./fbcli --password-file "$WORK_DIR/password" ls /
+assert "logout" ./fbcli logout
+assert "--password-file beats FILEBROWSER_PASSWORD" env FILEBROWSER_PASSWORD=wrong-password ./fbcli --password-file "$WORK_DIR/password" ls /
+assert "logout" ./fbcli logout
+assert "--password-file - reads standard input" bash -c "cat '$WORK_DIR/password' | ${NOPASS[*]} ./fbcli --password-file - ls /"
+assert "logout" ./fbcli logout
+assert_contains "show names the password file" "\[--password-file\]" "${NOPASS[@]}" ./fbcli --password-file "$WORK_DIR/password" show
+assert_fails "A wrong password file fails" "${NOPASS[@]}" ./fbcli --password-file "$WORK_DIR/wrong" ls /
+assert_fails "A missing password file fails" "${NOPASS[@]}" ./fbcli --password-file "$WORK_DIR/missing" ls /
+
+step "Testing password_command"
+assert "password_command supplies the password" "${NOPASS[@]}" ./fbcli --config "$WORK_DIR/config.toml" ls /
+assert "logout" ./fbcli --config "$WORK_DIR/config.toml" logout
+
+step "Testing .netrc"
+NETRC_ENV=(env -u FILEBROWSER_PASSWORD -u FILEBROWSER_USERNAME -u FBCLI_PROFILE -u FBCLI_CONFIG NETRC="$WORK_DIR/netrc")
+assert "~/.netrc supplies the username and password" "${NETRC_ENV[@]}" ./fbcli ls /
+assert "logout" ./fbcli logout
+assert_contains "show names the .netrc file" "^Username: $FILEBROWSER_USERNAME \[.*netrc\]" "${NETRC_ENV[@]}" ./fbcli show
+assert_fails "FILEBROWSER_PASSWORD beats ~/.netrc" "${NETRC_ENV[@]}" FILEBROWSER_PASSWORD=wrong-password ./fbcli ls /
+assert "logout" ./fbcli logout
+
+step "Testing precedence with a selected profile"
+# FILEBROWSER_PASSWORD belongs to FILEBROWSER_URL, not to a profile's server
+printf '[profiles.cmd]\nurl = "%s"\nusername = "%s"\npassword_command = "cat %s"\n\n[profiles.plain]\nurl = "%s"\nusername = "%s"\n' "$FILEBROWSER_URL" "$FILEBROWSER_USERNAME" "$WORK_DIR/password" "$FILEBROWSER_URL" "$FILEBROWSER_USERNAME" > "$WORK_DIR/profiles.toml"
+PROFILE_ENV=(env -u FBCLI_PROFILE -u FBCLI_CONFIG FILEBROWSER_PASSWORD=wrong-password NETRC="$WORK_DIR/no-netrc")
+assert "password_command of --profile beats FILEBROWSER_PASSWORD" "${PROFILE_ENV[@]}" ./fbcli --config "$WORK_DIR/profiles.toml" --profile cmd ls /
+assert "logout" ./fbcli --config "$WORK_DIR/profiles.toml" --profile cmd logout
+assert_contains "show names the profile's password_command" "^Password command: cat .* \[profile cmd\]" "${PROFILE_ENV[@]}" ./fbcli --config "$WORK_DIR/profiles.toml" --profile cmd show
+assert "~/.netrc beats FILEBROWSER_PASSWORD with --profile" "${PROFILE_ENV[@]}" NETRC="$WORK_DIR/netrc" ./fbcli --config "$WORK_DIR/profiles.toml" --profile plain ls /
+assert "logout" ./fbcli --config "$WORK_DIR/profiles.toml" --profile plain logout
+
+# Cleanup logs in again, which must not recreate the removed cache
+if [ -n "$SAVED_CACHE_HOME" ]; then
+    export XDG_CACHE_HOME="$SAVED_CACHE_HOME"
+else
+    unset XDG_CACHE_HOME
+fi
+
+finish_test
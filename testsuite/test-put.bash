@@ -0,0 +1,49 @@
+#!/usr/bin/env bash
+# Test script for uploading standard input
+# Tests put - with small input, chunked input, dry runs and password sources
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "put - command"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-put-$TEST_ID"
+LOCAL_FILE="put-$TEST_ID.bin"
+track_remote "$REMOTE_DIR"
+
+step "Testing small input"
+assert "put - uploads standard input" bash -c "echo 'hello from stdin' | ./fbcli put - '$REMOTE_DIR/sub/hello.txt'"
+assert_contains "Uploaded file holds the input" "^hello from stdin$" ./fbcli cat "$REMOTE_DIR/sub/hello.txt"
+assert "upload - uploads empty input" bash -c ": | ./fbcli upload - '$REMOTE_DIR/empty.txt'"
+assert_contains "Empty input makes an empty file" "empty.txt" ./fbcli ls -s "$REMOTE_DIR"
+
+step "Testing chunked input"
+head -c 3000000 /dev/urandom > "$LOCAL_FILE"
+track_local "$LOCAL_FILE"
+assert "put - streams input in chunks" bash -c "cat '$LOCAL_FILE' | ./fbcli --chunk-size 1M put - '$REMOTE_DIR/big.bin'"
+assert "Chunked upload matches the input" cmp -s "$LOCAL_FILE" <(./fbcli cat "$REMOTE_DIR/big.bin")
+
+step "Testing dry run"
+assert_contains "put --dry-run reports the upload" "Would upload the input" bash -c "echo x | ./fbcli put --dry-run - '$REMOTE_DIR/dry.txt'"
+assert_fails "put --dry-run creates nothing" ./fbcli cat "$REMOTE_DIR/dry.txt"
+
+step "Testing standard input as the password source"
+assert_fails "put - rejects --password-file -" bash -c "echo x | ./fbcli --password-file - put - '$REMOTE_DIR/twice.txt'"
+assert_contains "put - explains the clash with --password-file -" "cannot both be standard input" bash -c "echo x | ./fbcli --password-file - put - '$REMOTE_DIR/twice.txt' 2>&1; true"
+# Without a cached token or any password source, a login would prompt
+NO_PASSWORD_DIR="put-nopass-$TEST_ID"
+mkdir -p "$NO_PASSWORD_DIR"
+track_local "$NO_PASSWORD_DIR"
+NO_PASSWORD_ENV=(env -u FILEBROWSER_PASSWORD -u FBCLI_PROFILE -u FBCLI_CONFIG XDG_CACHE_HOME="$PWD/$NO_PASSWORD_DIR" XDG_CONFIG_HOME="$PWD/$NO_PASSWORD_DIR" NETRC="$PWD/$NO_PASSWORD_DIR/netrc")
+assert_fails "put - does not prompt for the password" "${NO_PASSWORD_ENV[@]}" bash -c "echo secret | ./fbcli put - '$REMOTE_DIR/twice.txt'"
+assert_contains "put - explains why it cannot prompt" "cannot ask for the password" "${NO_PASSWORD_ENV[@]}" bash -c "echo secret | ./fbcli put - '$REMOTE_DIR/twice.txt' 2>&1; true"
+assert_fails "Nothing was uploaded" ./fbcli cat "$REMOTE_DIR/twice.txt"
+printf '%s\n' "$FILEBROWSER_PASSWORD" > "$NO_PASSWORD_DIR/password"
+assert "put - works with a password file" "${NO_PASSWORD_ENV[@]}" bash -c "echo 'after login' | ./fbcli --password-file '$NO_PASSWORD_DIR/password' put - '$REMOTE_DIR/twice.txt'"
+assert_contains "Upload after the login holds the input" "^after login$" ./fbcli cat "$REMOTE_DIR/twice.txt"
+
+step "Testing error handling"
+assert_fails "put - fails without a remote path" bash -c "echo x | ./fbcli put -"
+
+finish_test
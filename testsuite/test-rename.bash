@@ -30,11 +30,11 @@ assert "rename command works" ./fbcli rename "$OLD_FILE" "$NEW_FILE"
 assert_remote_not_exists "Original file moved" "$OLD_FILE"
 assert_remote_exists "File renamed successfully" "$NEW_FILE"
 
-step "Testing file rename with mv alias"
+step "Testing file rename with mv"
 OLD_FILE2="$REMOTE_DIR/$LOCAL_SETUP_DIR/subdir/nested.txt"
 NEW_FILE2="$REMOTE_DIR/$LOCAL_SETUP_DIR/subdir/moved.txt"
 assert_remote_exists "Nested file exists" "$OLD_FILE2"
-assert "mv alias works" ./fbcli mv "$OLD_FILE2" "$NEW_FILE2"
+assert "mv command works" ./fbcli mv "$OLD_FILE2" "$NEW_FILE2"
 assert_remote_not_exists "Original nested file moved" "$OLD_FILE2"
 assert_remote_exists "Nested file renamed" "$NEW_FILE2"
 
@@ -55,8 +55,43 @@ assert_remote_not_exists "Original directory moved" "$OLD_DIR"
 assert_remote_exists "Directory renamed successfully" "$NEW_DIR"
 assert_remote_exists "Directory content preserved" "$NEW_DIR/content.txt"
 
+step "Testing moves into directories"
+REMOTE_DIR3="/test-mv-into-$TEST_ID"
+create_test_file "mv-a-$TEST_ID.txt" "file a"
+create_test_file "mv-b-$TEST_ID.txt" "file b"
+assert "Upload first file" ./fbcli upload "mv-a-$TEST_ID.txt" "$REMOTE_DIR3"
+assert "Upload second file" ./fbcli upload "mv-b-$TEST_ID.txt" "$REMOTE_DIR3"
+assert "Create target directory" ./fbcli mkdir "$REMOTE_DIR3/target"
+track_remote "$REMOTE_DIR3"
+assert "mv moves several files into a directory" ./fbcli mv "$REMOTE_DIR3/mv-a-$TEST_ID.txt" "$REMOTE_DIR3/mv-b-$TEST_ID.txt" "$REMOTE_DIR3/target"
+assert_remote_exists "First file moved into the directory" "$REMOTE_DIR3/target/mv-a-$TEST_ID.txt"
+assert_remote_exists "Second file moved into the directory" "$REMOTE_DIR3/target/mv-b-$TEST_ID.txt"
+assert_remote_not_exists "Files left their old directory" "$REMOTE_DIR3/mv-a-$TEST_ID.txt"
+
+step "Testing existing destinations"
+A_FILE="$REMOTE_DIR3/target/mv-a-$TEST_ID.txt"
+B_FILE="$REMOTE_DIR3/target/mv-b-$TEST_ID.txt"
+assert_fails "mv onto an existing file fails" ./fbcli mv "$A_FILE" "$B_FILE"
+assert_remote_exists "Failed move kept the source" "$A_FILE"
+assert "mv -n skips an existing file" ./fbcli mv -n "$A_FILE" "$B_FILE"
+assert_contains "Skipped move left the file alone" "^file b$" ./fbcli cat "$B_FILE"
+assert "mv --auto-rename keeps both" ./fbcli mv --auto-rename "$A_FILE" "$B_FILE"
+assert_contains "Server picked a free name" "mv-b-$TEST_ID(1).txt" ./fbcli ls -s "$REMOTE_DIR3/target"
+assert "mv -f replaces an existing file" ./fbcli mv -f "$REMOTE_DIR3/target/mv-b-$TEST_ID(1).txt" "$B_FILE"
+assert_contains "Replaced file has the moved content" "^file a$" ./fbcli cat "$B_FILE"
+
+step "Testing that rename only renames"
+assert_fails "rename onto an existing directory fails" ./fbcli rename "$B_FILE" "$REMOTE_DIR3"
+assert_remote_exists "Failed rename kept the source" "$B_FILE"
+assert_remote_not_exists "rename did not move into the directory" "$REMOTE_DIR3/mv-b-$TEST_ID.txt"
+assert_fails "rename takes no flags" ./fbcli rename -f "$B_FILE" "$REMOTE_DIR3/other.txt"
+assert_fails "rename takes exactly two paths" ./fbcli rename "$B_FILE" "$REMOTE_DIR3/x.txt" "$REMOTE_DIR3/y.txt"
+assert_remote_exists "Rejected renames kept the source" "$B_FILE"
+
 step "Testing error handling"
 assert_fails "rename fails on non-existent file" ./fbcli rename "/non-existent-$TEST_ID.txt" "/also-non-existent-$TEST_ID.txt"
 assert_fails "mv fails on non-existent file" ./fbcli mv "/non-existent-$TEST_ID.txt" "/also-non-existent-$TEST_ID.txt"
+assert_fails "mv of several files needs a directory" ./fbcli mv "$B_FILE" "$REMOTE_DIR3/x" "$REMOTE_DIR3/y.txt"
+assert_fails "mv of a directory into itself fails" ./fbcli mv "$REMOTE_DIR3/target" "$REMOTE_DIR3/target/inner"
 
 finish_test
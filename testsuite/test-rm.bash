@@ -115,7 +115,15 @@ assert "delete -i works" ./fbcli delete -i ".*\\.log$" "$REMOTE_DIR5/$LOCAL_SETU
 assert_remote_not_exists "Non-ignored file deleted with delete -i" "$REMOTE_DIR5/$LOCAL_SETUP_DIR5/delete-this.txt"
 assert_remote_exists "Log file ignored with delete -i" "$REMOTE_DIR5/$LOCAL_SETUP_DIR5/ignore-pattern.log"
 
-# Test 8: Error handling
+# Test 8: Dry run
+step "Testing rm dry run"
+assert_contains "rm --dry-run plans the file deletion" "Would delete $REMOTE_DIR4/$LOCAL_SETUP_DIR4/ignore-me.txt" ./fbcli rm --dry-run "$REMOTE_DIR4"
+assert_contains "rm --dry-run plans the directory deletion" "Would delete $REMOTE_DIR4/$" ./fbcli rm --dry-run "$REMOTE_DIR4"
+assert_contains "rm --dry-run says nothing changed" "Dry run: nothing was changed." ./fbcli rm --dry-run "$REMOTE_DIR4"
+assert_remote_exists "Dry run keeps the file" "$REMOTE_DIR4/$LOCAL_SETUP_DIR4/ignore-me.txt"
+assert_remote_exists "Dry run keeps the directory" "$REMOTE_DIR4"
+
+# Test 9: Error handling
 step "Testing error handling"
 assert_fails "rm fails on non-existent file" ./fbcli rm "/non-existent-file-$TEST_ID.txt"
 assert_fails "delete fails on non-existent file" ./fbcli delete "/non-existent-file-$TEST_ID.txt"
@@ -0,0 +1,41 @@
+#!/usr/bin/env bash
+# Test script for search command
+# Tests server-side name queries, type filters, ignore patterns and JSON output
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "search command"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-search-$TEST_ID"
+LOCAL_DIR="search-$TEST_ID"
+
+step "Setting up test environment"
+mkdir -p "$LOCAL_DIR/pics/Holiday" "$LOCAL_DIR/docs"
+echo "png" > "$LOCAL_DIR/pics/Holiday/beach.png"
+echo "notes" > "$LOCAL_DIR/pics/holiday.txt"
+echo "plan" > "$LOCAL_DIR/docs/Holiday-plan.md"
+track_local "$LOCAL_DIR"
+assert "Upload test tree" ./fbcli upload "$LOCAL_DIR" "$REMOTE_DIR"
+track_remote "$REMOTE_DIR"
+ROOT="$REMOTE_DIR/$LOCAL_DIR"
+
+step "Testing search"
+assert_contains "search finds files by name" "^$ROOT/pics/holiday.txt$" ./fbcli search "$ROOT" holiday
+assert_contains "search finds directories" "^$ROOT/pics/Holiday$" ./fbcli search "$ROOT" holiday
+assert_contains "search ignores case by default" "^$ROOT/docs/Holiday-plan.md$" ./fbcli search "$ROOT" holiday
+assert_not_contains "search leaves out other names" "beach" ./fbcli search "$ROOT" holiday
+assert_not_contains "search --case-sensitive matches the case" "holiday.txt" ./fbcli search "$ROOT" Holiday --case-sensitive
+assert_contains "search --type image finds images" "^$ROOT/pics/Holiday/beach.png$" ./fbcli search "$ROOT" beach --type image
+assert_not_contains "search --type image leaves out other files" "holiday.txt" ./fbcli search "$ROOT" holiday --type image
+assert_not_contains "search -i leaves out matching entries" "pics" ./fbcli search -i "^pics$" "$ROOT" holiday
+assert_contains "search results work with other commands" "notes" bash -c "./fbcli search '$ROOT' holiday.txt | xargs ./fbcli cat"
+assert_contains "search prints JSON records" "\"type\":\"match\",\"path\":\"$ROOT/pics/Holiday/beach.png\",\"isDir\":false" ./fbcli --output json search "$ROOT" beach
+
+step "Testing error handling"
+assert_fails "search fails on non-existent path" ./fbcli search "$REMOTE_DIR/non-existent" holiday
+assert_fails "search rejects an unknown type" ./fbcli search "$ROOT" holiday --type spreadsheet
+assert_fails "search requires a query" ./fbcli search
+
+finish_test
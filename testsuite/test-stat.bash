@@ -0,0 +1,45 @@
+#!/usr/bin/env bash
+# Test script for stat command
+# Tests metadata of files and directories, checksums and JSON output
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "stat command"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-stat-$TEST_ID"
+LOCAL_FILE="stat-$TEST_ID.txt"
+
+step "Setting up test environment"
+create_test_file "$LOCAL_FILE" "stat me"
+assert "Upload test file" ./fbcli upload "$LOCAL_FILE" "$REMOTE_DIR"
+track_remote "$REMOTE_DIR"
+REMOTE_FILE="$REMOTE_DIR/$LOCAL_FILE"
+
+step "Testing file metadata"
+assert_contains "stat shows the path" "^Path: *$REMOTE_FILE$" ./fbcli stat "$REMOTE_FILE"
+assert_contains "stat shows the type" "^Type: *file$" ./fbcli stat "$REMOTE_FILE"
+assert_contains "stat shows the size" "^Size: *8 " ./fbcli stat "$REMOTE_FILE"
+assert_contains "stat shows the MIME type" "^MIME type: *text/plain" ./fbcli stat "$REMOTE_FILE"
+assert_contains "stat shows a directory" "^Type: *directory$" ./fbcli stat "$REMOTE_DIR"
+
+step "Testing checksums"
+SHA256=$(sha256sum "$LOCAL_FILE" | cut -d' ' -f1)
+MD5=$(md5sum "$LOCAL_FILE" | cut -d' ' -f1)
+assert_not_contains "stat computes no checksum by default" "SHA256" ./fbcli stat "$REMOTE_FILE"
+assert_contains "stat -c sha256 matches the local file" "^SHA256: *$SHA256$" ./fbcli stat -c sha256 "$REMOTE_FILE"
+assert_contains "stat -c all includes md5" "^MD5: *$MD5$" ./fbcli stat -c all "$REMOTE_FILE"
+assert_contains "stat --checksum takes a list" "^MD5: *$MD5$" ./fbcli stat --checksum sha256,md5 "$REMOTE_FILE"
+assert_contains "stat -c skips directories" "^Type: *directory$" ./fbcli stat -c md5 "$REMOTE_DIR"
+
+step "Testing JSON output"
+assert_contains "stat prints a JSON record" "\"type\":\"stat\".*\"path\":\"$REMOTE_FILE\"" ./fbcli --output json stat "$REMOTE_FILE"
+assert_contains "JSON record holds checksums" "\"sha256\":\"$SHA256\"" ./fbcli --output json stat -c sha256 "$REMOTE_FILE"
+
+step "Testing error handling"
+assert_fails "stat fails on non-existent path" ./fbcli stat "$REMOTE_DIR/non-existent.txt"
+assert_fails "stat fails on unknown algorithm" ./fbcli stat -c crc32 "$REMOTE_FILE"
+assert_fails "stat fails without a path" ./fbcli stat
+
+finish_test
@@ -0,0 +1,50 @@
+#!/usr/bin/env bash
+# Test script for sum command and --verify
+# Tests checksums of files and directories, checking checksum files and verified transfers
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "sum command"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-sum-$TEST_ID"
+LOCAL_DIR="sum-$TEST_ID"
+MANIFEST="sum-$TEST_ID.sha256"
+
+step "Setting up test environment"
+create_test_file "$LOCAL_DIR/a.txt" "first file"
+create_test_file "$LOCAL_DIR/sub/b.txt" "second file"
+track_local "$LOCAL_DIR"
+(cd "$LOCAL_DIR" && sha256sum a.txt sub/b.txt) > "$MANIFEST"
+track_local "$MANIFEST"
+assert "Upload with --verify" ./fbcli upload --verify "$LOCAL_DIR" "$REMOTE_DIR"
+track_remote "$REMOTE_DIR"
+REMOTE_FILE="$REMOTE_DIR/$LOCAL_DIR/a.txt"
+
+step "Testing sum"
+SHA256=$(sha256sum "$LOCAL_DIR/a.txt" | cut -d' ' -f1)
+MD5=$(md5sum "$LOCAL_DIR/a.txt" | cut -d' ' -f1)
+assert_contains "sum prints sha256sum lines" "^$SHA256  $REMOTE_FILE$" ./fbcli sum "$REMOTE_FILE"
+assert_contains "sum -a md5 uses md5" "^$MD5  $REMOTE_FILE$" ./fbcli sum -a md5 "$REMOTE_FILE"
+assert_contains "sum walks directories" "  $REMOTE_DIR/$LOCAL_DIR/sub/b.txt$" ./fbcli sum "$REMOTE_DIR"
+
+step "Testing sum -c"
+assert_contains "sum -c reports matching files" "^sub/b.txt: OK$" ./fbcli sum -c "$MANIFEST" "$REMOTE_DIR/$LOCAL_DIR"
+assert_not_contains "sum -q -c hides matching files" "OK" ./fbcli sum -q -c "$MANIFEST" "$REMOTE_DIR/$LOCAL_DIR"
+echo "changed" > "$LOCAL_DIR/a.txt"
+assert "Upload a changed file" ./fbcli upload "$LOCAL_DIR/a.txt" "$REMOTE_DIR/$LOCAL_DIR"
+assert_fails "sum -c fails when a file changed" ./fbcli sum -c "$MANIFEST" "$REMOTE_DIR/$LOCAL_DIR"
+assert_contains "sum -c reports changed files" "^a.txt: FAILED$" bash -c "./fbcli sum -c '$MANIFEST' '$REMOTE_DIR/$LOCAL_DIR'; true"
+assert_contains "sum -c reports missing files" "^a.txt: MISSING$" bash -c "./fbcli sum -c '$MANIFEST' '$REMOTE_DIR'; true"
+
+step "Testing download --verify"
+assert "Download with --verify" ./fbcli download --verify "$REMOTE_DIR/$LOCAL_DIR/sub/b.txt" "$LOCAL_DIR/b-copy.txt"
+assert "Verified download matches" cmp -s "$LOCAL_DIR/sub/b.txt" "$LOCAL_DIR/b-copy.txt"
+
+step "Testing error handling"
+assert_fails "sum fails on non-existent file" ./fbcli sum "$REMOTE_DIR/non-existent.txt"
+assert_fails "sum fails on unknown algorithm" ./fbcli sum -a crc32 "$REMOTE_FILE"
+assert_fails "--verify is rejected by other commands" ./fbcli ls --verify "$REMOTE_DIR"
+
+finish_test
@@ -34,10 +34,12 @@ assert_exists "Nested remote file synced locally" "$LOCAL_SYNC_DIR/subdir/nested
 assert_exists "JSON remote file synced locally" "$LOCAL_SYNC_DIR/data-remote.json"
 
 step "Testing syncfrom with remote file modifications"
-# Modify a remote file by uploading a new version
-create_test_file "updated-remote1.txt" "updated remote content 1"
-assert "Update remote file" ./fbcli upload "updated-remote1.txt" "$REMOTE_DIR/$LOCAL_SETUP_DIR/remote1.txt"
-track_local "updated-remote1.txt"
+# Modify a remote file by uploading a new version over it
+UPDATE_DIR="update-syncfrom-$TEST_ID"
+mkdir -p "$UPDATE_DIR"
+track_local "$UPDATE_DIR"
+create_test_file "$UPDATE_DIR/remote1.txt" "updated remote content 1, now longer"
+assert "Update remote file" ./fbcli upload "$UPDATE_DIR/remote1.txt" "$REMOTE_DIR/$LOCAL_SETUP_DIR"
 
 # Add a new remote file
 create_test_file "new-remote.txt" "new remote file content"
@@ -46,8 +48,25 @@ track_local "new-remote.txt"
 
 assert "Sync updated remote files" ./fbcli syncfrom "$REMOTE_DIR/$LOCAL_SETUP_DIR" "$LOCAL_SYNC_DIR"
 assert_exists "Updated remote file synced" "$LOCAL_SYNC_DIR/remote1.txt"
+assert_contains "Updated remote content synced" "updated remote content 1, now longer" cat "$LOCAL_SYNC_DIR/remote1.txt"
 assert_exists "New remote file synced" "$LOCAL_SYNC_DIR/new-remote.txt"
 
+step "Testing parallel syncfrom"
+PAR_DIR="parallel-syncfrom-$TEST_ID"
+assert_contains "syncfrom -j 4 transfers every file" "transferred=7 skipped=0 failed=0" ./fbcli -j 4 syncfrom "$REMOTE_DIR/$LOCAL_SETUP_DIR" "$PAR_DIR"
+track_local "$PAR_DIR"
+assert "Parallel sync matches the sequential one" diff -r "$LOCAL_SYNC_DIR" "$PAR_DIR"
+assert_contains "syncfrom -j 4 skips files in sync" "transferred=0 skipped=7 failed=0" ./fbcli -j 4 syncfrom "$REMOTE_DIR/$LOCAL_SETUP_DIR" "$PAR_DIR"
+
+step "Testing syncfrom dry run"
+create_test_file "$LOCAL_SYNC_DIR/local-only.txt" "only on this side"
+create_test_file "$LOCAL_SYNC_DIR/subdir/local-nested.txt" "also only on this side"
+assert_contains "syncfrom --dry-run plans the deletions" "Would delete local $LOCAL_SYNC_DIR/local-only.txt (not in remote)" ./fbcli syncfrom --dry-run "$REMOTE_DIR/$LOCAL_SETUP_DIR" "$LOCAL_SYNC_DIR"
+assert_contains "syncfrom --dry-run plans nested deletions" "Would delete local $LOCAL_SYNC_DIR/subdir/local-nested.txt (not in remote)" ./fbcli syncfrom --dry-run "$REMOTE_DIR/$LOCAL_SETUP_DIR" "$LOCAL_SYNC_DIR"
+assert_exists "Dry run keeps the local-only file" "$LOCAL_SYNC_DIR/local-only.txt"
+assert_exists "Dry run keeps the nested local-only file" "$LOCAL_SYNC_DIR/subdir/local-nested.txt"
+rm -f "$LOCAL_SYNC_DIR/local-only.txt" "$LOCAL_SYNC_DIR/subdir/local-nested.txt"
+
 step "Testing syncfrom with ignore pattern"
 # Setup remote files with different types
 LOCAL_SETUP_DIR2="setup-ignore-syncfrom-$TEST_ID"
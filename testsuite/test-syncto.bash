@@ -37,6 +37,34 @@ assert "Sync updated files" ./fbcli syncto "$LOCAL_DIR" "$REMOTE_DIR"
 assert_remote_exists "Updated file synced" "$REMOTE_DIR/file1.txt"
 assert_remote_exists "New file synced" "$REMOTE_DIR/new-file.txt"
 
+step "Testing syncto dry run"
+DRY_DIR="dry-syncto-$TEST_ID"
+mkdir -p "$DRY_DIR"
+track_local "$DRY_DIR"
+create_test_file "$DRY_DIR/file1.txt" "dry run content of the first file"
+assert_contains "syncto --dry-run plans the deletions" "Would delete remote $REMOTE_DIR/file2.txt (not in source)" ./fbcli syncto --dry-run "$DRY_DIR" "$REMOTE_DIR"
+assert_contains "syncto --dry-run plans nested deletions" "Would delete remote $REMOTE_DIR/subdir/\? (not in source)" ./fbcli syncto --dry-run "$DRY_DIR" "$REMOTE_DIR"
+assert_contains "syncto --dry-run plans the updates" "Would upload $DRY_DIR/file1.txt to $REMOTE_DIR/file1.txt (size differs)" ./fbcli syncto --dry-run "$DRY_DIR" "$REMOTE_DIR"
+assert_remote_exists "Dry run keeps file2" "$REMOTE_DIR/file2.txt"
+assert_remote_exists "Dry run keeps the nested file" "$REMOTE_DIR/subdir/nested.txt"
+assert_remote_exists "Dry run keeps new-file" "$REMOTE_DIR/new-file.txt"
+assert_contains "Dry run keeps the remote content" "updated content of file1" ./fbcli cat "$REMOTE_DIR/file1.txt"
+
+step "Testing parallel syncto"
+PAR_DIR="parallel-syncto-$TEST_ID"
+REMOTE_PAR="/test-syncto-parallel-$TEST_ID"
+create_test_dir "$PAR_DIR" 8
+for i in 1 2 3 4; do
+    create_test_file "$PAR_DIR/sub$i/nested.txt" "nested content $i"
+done
+assert_contains "syncto -j 4 transfers every file" "transferred=12 skipped=0 failed=0" ./fbcli -j 4 syncto "$PAR_DIR" "$REMOTE_PAR"
+track_remote "$REMOTE_PAR"
+assert_remote_exists "First file synced" "$REMOTE_PAR/file1.txt"
+assert_remote_exists "Last file synced" "$REMOTE_PAR/file8.txt"
+assert_remote_exists "Nested file synced" "$REMOTE_PAR/sub4/nested.txt"
+assert_contains "Parallel sync keeps the content" "nested content 3" ./fbcli cat "$REMOTE_PAR/sub3/nested.txt"
+assert_contains "syncto -j 4 skips files in sync" "transferred=0 skipped=12 failed=0" ./fbcli -j 4 syncto "$PAR_DIR" "$REMOTE_PAR"
+
 step "Testing syncto with ignore pattern"
 LOCAL_DIR2="local-ignore-sync-$TEST_ID"
 REMOTE_DIR2="/test-syncto-ignore-$TEST_ID"
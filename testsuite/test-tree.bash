@@ -0,0 +1,47 @@
+#!/usr/bin/env bash
+# Test script for tree command and ls -R
+# Tests recursive listings, depth limits, ignore patterns and flat output
+
+source "$(dirname "$0")/framework.bash"
+
+init_test "tree command"
+
+# Generate unique test identifiers
+TEST_ID=$(gen_id)
+REMOTE_DIR="/test-tree-$TEST_ID"
+LOCAL_DIR="tree-$TEST_ID"
+
+step "Setting up test environment"
+create_test_file "$LOCAL_DIR/top.txt" "top"
+create_test_file "$LOCAL_DIR/sub/middle.txt" "middle"
+create_test_file "$LOCAL_DIR/sub/deep/bottom.txt" "bottom"
+create_test_file "$LOCAL_DIR/skip/hidden.txt" "hidden"
+track_local "$LOCAL_DIR"
+assert "Upload test tree" ./fbcli upload "$LOCAL_DIR" "$REMOTE_DIR"
+track_remote "$REMOTE_DIR"
+ROOT="$REMOTE_DIR/$LOCAL_DIR"
+
+step "Testing tree"
+assert_contains "tree shows nested files" "bottom.txt" ./fbcli tree "$ROOT"
+assert_contains "tree draws branches" "└── " ./fbcli tree "$ROOT"
+assert_contains "tree prints totals" "^3 directories, 4 files" ./fbcli tree "$ROOT"
+assert_not_contains "tree -L stops descending" "bottom.txt" ./fbcli tree -L 2 "$ROOT"
+assert_contains "tree -L still shows the last level" "middle.txt" ./fbcli tree -L 2 "$ROOT"
+assert_not_contains "tree -i leaves out matching entries" "hidden.txt" ./fbcli tree -i "^skip$" "$ROOT"
+
+step "Testing flat output"
+assert_contains "tree --flat prints full paths" "^$ROOT/sub/deep/bottom.txt$" ./fbcli tree --flat "$ROOT"
+assert_contains "tree -s marks directories" "^$ROOT/sub/deep/$" ./fbcli tree -s "$ROOT"
+assert_contains "tree --dirs-first lists directories first" "^$ROOT/skip/$" bash -c "./fbcli tree --flat --dirs-first -L 1 '$ROOT' | head -1"
+
+step "Testing ls -R"
+assert_contains "ls -R prints a header per directory" "^$ROOT/sub/deep:$" ./fbcli ls -R -s "$ROOT"
+assert_contains "ls -R lists nested files" "^bottom.txt$" ./fbcli ls -R -s "$ROOT"
+assert_contains "ls -R -l shows details" "^Name " ./fbcli ls -R -l "$ROOT"
+assert_contains "ls -R in JSON includes nested entries" "\"path\":\"$ROOT/sub/deep/bottom.txt\"" ./fbcli --output json ls -R "$ROOT"
+
+step "Testing error handling"
+assert_fails "tree fails on non-existent directory" ./fbcli tree "$REMOTE_DIR/non-existent"
+assert_fails "tree rejects an invalid depth" ./fbcli tree -L 0 "$ROOT"
+
+finish_test
@@ -50,6 +50,26 @@ assert_remote_exists "Include file uploaded" "$REMOTE_DIR3/$LOCAL_DIR2/include.t
 assert_remote_exists "Data file uploaded" "$REMOTE_DIR3/$LOCAL_DIR2/keep.data"
 assert_remote_not_exists "Log file ignored" "$REMOTE_DIR3/$LOCAL_DIR2/ignore.log"
 
+step "Testing chunked upload"
+BIG_FILE="big-$TEST_ID.bin"
+head -c 3500000 /dev/urandom > "$BIG_FILE"
+track_local "$BIG_FILE"
+BIG_SUM=$(sha256sum "$BIG_FILE" | cut -d' ' -f1)
+REMOTE_DIR5="/test-upload-chunked-$TEST_ID"
+assert "Upload a file in 1M chunks" ./fbcli --chunk-size 1M --chunk-threshold 1M upload "$BIG_FILE" "$REMOTE_DIR5"
+track_remote "$REMOTE_DIR5"
+assert_contains "Chunked upload is complete" "^$BIG_SUM " ./fbcli sum "$REMOTE_DIR5/$BIG_FILE"
+assert "Upload again in chunks with --verify" ./fbcli --chunk-size 1M --chunk-threshold 1M upload --verify "$BIG_FILE" "$REMOTE_DIR5"
+assert_contains "Chunked upload replaced the file" "^$BIG_SUM " ./fbcli sum "$REMOTE_DIR5/$BIG_FILE"
+assert "Upload below the threshold in one request" ./fbcli --chunk-size 1M --chunk-threshold 10M upload "$BIG_FILE" "$REMOTE_DIR5/whole"
+assert_contains "Whole upload is complete" "^$BIG_SUM " ./fbcli sum "$REMOTE_DIR5/whole/$BIG_FILE"
+
+step "Testing upload dry run"
+REMOTE_DIR4="/test-upload-dry-$TEST_ID"
+assert_contains "upload --dry-run plans the directory" "Would create remote directory $REMOTE_DIR4/$LOCAL_DIR" ./fbcli upload --dry-run "$LOCAL_DIR" "$REMOTE_DIR4"
+assert_contains "upload --dry-run plans the files" "Would upload $LOCAL_DIR/subdir/nested.txt to $REMOTE_DIR4/$LOCAL_DIR/subdir/nested.txt (new file)" ./fbcli upload --dry-run "$LOCAL_DIR" "$REMOTE_DIR4"
+assert_remote_not_exists "Dry run creates nothing" "$REMOTE_DIR4"
+
 step "Testing error handling"
 assert_fails "Upload fails on non-existent file" ./fbcli upload "/non-existent-file-$TEST_ID.txt" "$REMOTE_DIR"
 
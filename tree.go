@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/johnwmail/fbcli/filebrowser"
+)
+
+// treeOptions are the flags of tree and ls -R
+type treeOptions struct {
+	recursive bool // ls -R
+	depth     int  // levels below the top to show; 0 for all
+	dirsFirst bool
+	flat      bool
+}
+
+// treeNode is an entry of a remote tree; directories hold their entries
+// unless the depth limit stopped the walk, or err says why they could not be
+// listed
+type treeNode struct {
+	item     filebrowser.RemoteItem
+	path     string
+	children []*treeNode
+	ignored  int // entries left out of children by the ignore pattern
+	err      error
+}
+
+// treeTotals counts the entries of a tree
+type treeTotals struct {
+	dirs, files int
+	size        int64
+}
+
+// parseTreeArgs separates the flags of tree (-L, --dirs-first, --flat) or ls
+// (the same and -R) from the remote path
+func parseTreeArgs(cmd string, args []string) (treeOptions, []string, error) {
+	var opts treeOptions
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch {
+		case cmd != "tree" && args[i] == "-R":
+			opts.recursive = true
+			continue
+		case name == "-L" || name == "--depth":
+		case args[i] == "--dirs-first":
+			opts.dirsFirst = true
+			continue
+		case args[i] == "--flat":
+			opts.flat = true
+			continue
+		default:
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return opts, nil, fmt.Errorf("invalid depth %q", value)
+		}
+		opts.depth = n
+	}
+	return opts, rest, nil
+}
+
+// fetchTree lists remotePath and, down to opts.depth levels, the directories
+// below it. Entries matching ignoreRegex are left out together with their
+// contents and only counted in ignored. Directories that cannot be listed keep
+// the error.
+func fetchTree(ctx context.Context, client *filebrowser.Client, remotePath string, ignoreRegex *regexp.Regexp, opts treeOptions, level int) (nodes []*treeNode, ignored int, err error) {
+	all, err := listEntries(ctx, client, remotePath, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := all[:0]
+	for _, item := range all {
+		if ignoreRegex != nil && shouldIgnoreRegex(strings.TrimRight(item.Name, "\r\n"), ignoreRegex) {
+			ignored++
+			continue
+		}
+		items = append(items, item)
+	}
+	if !opts.recursive {
+		// tree sorts by name; ls -R keeps the order of ls
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	}
+	if opts.dirsFirst {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].IsDir && !items[j].IsDir })
+	}
+	nodes = make([]*treeNode, 0, len(items))
+	for _, item := range items {
+		node := &treeNode{item: item, path: path.Join(remotePath, item.Name)}
+		if item.IsDir && (opts.depth == 0 || level < opts.depth) {
+			if err := ctx.Err(); err != nil {
+				return nil, 0, err
+			}
+			node.children, node.ignored, node.err = fetchTree(ctx, client, node.path, ignoreRegex, opts, level+1)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, ignored, nil
+}
+
+// listTree prints remotePath and everything below it: as a tree with totals,
+// as one path per line with --flat or in script mode, or for ls -R as a
+// listing of each directory. In JSON mode it prints one array of all entries.
+func listTree(ctx context.Context, client *filebrowser.Client, remotePath string, ignoreRegex *regexp.Regexp, opts treeOptions, scriptMode, detailed bool) error {
+	nodes, _, err := fetchTree(ctx, client, remotePath, ignoreRegex, opts, 1)
+	if err != nil {
+		return err
+	}
+	// Directories that cannot be listed are reported inline when drawn
+	inline := jsonOut == nil && !opts.flat && (opts.recursive || !scriptMode)
+	var totals treeTotals
+	switch {
+	case jsonOut != nil:
+		entries := []entryRecord{}
+		walkTree(nodes, func(n *treeNode) {
+			entries = append(entries, newEntryRecord(path.Dir(n.path), n.item))
+		})
+		jsonOut.record(entries)
+	case opts.flat || (scriptMode && !opts.recursive):
+		walkTree(nodes, func(n *treeNode) {
+			fmt.Println(displayName(filebrowser.RemoteItem{Name: n.path, IsDir: n.item.IsDir}))
+		})
+	case opts.recursive:
+		printRecursive(remotePath, nodes, scriptMode, detailed)
+	default:
+		fmt.Println(colorBlue + remotePath + colorReset)
+		printTree(nodes, "", detailed, &totals)
+		fmt.Printf("\n%s, %s, %s\n", plural(totals.dirs, "directory", "directories"), plural(totals.files, "file", "files"), formatBytes(totals.size))
+	}
+	failed := 0
+	walkTree(nodes, func(n *treeNode) {
+		if n.err != nil {
+			failed++
+			if !inline {
+				warnList(n)
+			}
+		}
+	})
+	if failed > 0 {
+		return fmt.Errorf("%s could not be listed", plural(failed, "directory", "directories"))
+	}
+	return nil
+}
+
+// walkTree calls fn for every node in depth-first order
+func walkTree(nodes []*treeNode, fn func(*treeNode)) {
+	for _, n := range nodes {
+		fn(n)
+		walkTree(n.children, fn)
+	}
+}
+
+// printTree draws nodes like the Unix tree command, counting them in totals
+func printTree(nodes []*treeNode, prefix string, detailed bool, totals *treeTotals) {
+	for i, n := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+		name := displayName(n.item)
+		if n.item.IsDir {
+			name = colorBlue + name + colorReset
+			totals.dirs++
+		} else {
+			totals.files++
+			totals.size += n.item.Size
+		}
+		if detailed {
+			name = fmt.Sprintf("[%10s  %s]  %s", formatBytes(n.item.Size), formatModified(n.item.Modified), name)
+		}
+		if n.err != nil {
+			name += fmt.Sprintf("  [cannot list: %v]", n.err)
+		}
+		fmt.Println(prefix + branch + name)
+		printTree(n.children, prefix+indent, detailed, totals)
+	}
+}
+
+// printRecursive prints the listing of dir and of every directory below it,
+// each under a "path:" header, like ls -R
+func printRecursive(dir string, nodes []*treeNode, scriptMode, detailed bool) {
+	fmt.Printf("%s:\n", dir)
+	items := make([]filebrowser.RemoteItem, len(nodes))
+	for i, n := range nodes {
+		items[i] = n.item
+	}
+	if detailed {
+		printDetailed(items)
+	} else {
+		printNames(items, scriptMode)
+	}
+	for _, n := range nodes {
+		if !n.item.IsDir {
+			continue
+		}
+		if n.err != nil {
+			fmt.Printf("\n%s:\n", n.path)
+			warnList(n)
+		} else if n.children != nil {
+			fmt.Println()
+			printRecursive(n.path, n.children, scriptMode, detailed)
+		}
+	}
+}
+
+// warnList reports a directory that could not be listed
+func warnList(n *treeNode) {
+	if jsonOut != nil {
+		jsonOut.message(messageRecord{Type: "error", Message: fmt.Sprintf("cannot list %s: %v", n.path, n.err)})
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: cannot list %s: %v\n", n.path, n.err)
+}
+
+// plural formats n with the singular or plural form of a noun
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return strconv.Itoa(n) + " " + many
+}